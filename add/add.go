@@ -1,79 +1,76 @@
 package add
 
 import (
-	"crypto/rand"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
 
+	"github.com/jeremyphua/mypass/generate"
 	"github.com/jeremyphua/mypass/io"
-	"github.com/jeremyphua/mypass/pc"
-	"golang.org/x/crypto/nacl/box"
+	"github.com/jeremyphua/mypass/session"
+	"github.com/jeremyphua/mypass/vault"
 )
 
-func AddPassword(name string) {
-
-	HandleVaultExist()
+// Options of AddPassword, the username and password are prompted for when left nil
+type Options struct {
+	Username *string
+	Password *string
+	// Generate the password instead, it is never shown
+	Generate *generate.Options
+	// URL, notes and custom fields of the site
+	Details vault.Details
+}
 
-	var c io.ConfigFile
+// AddPassword adds a new site to v with the username and password given in opts or prompted for
+func AddPassword(v *vault.Vault, name string, opts Options) error {
 
-	pub, priv, err := box.GenerateKey(rand.Reader)
+	name, err := io.NormalizeSiteName(name)
 	if err != nil {
-		log.Fatalf("Could not generate site key: %s", err.Error())
+		return err
 	}
 
-	config, err := io.GetConfigFile()
-	if err != nil {
-		log.Fatalf("Could not get config file: %s", err.Error())
+	// an encrypted index can't be read or written without the master password
+	if err = session.UnlockIndex(v); err != nil {
+		return err
 	}
 
-	configContents, err := ioutil.ReadFile(config)
-	if err != nil {
-		log.Fatalf("Could not read config file contents: %s", err.Error())
+	// fail before prompting if the name is already taken
+	if _, err := v.Site(name); err == nil {
+		return fmt.Errorf("%w: %s", io.ErrDuplicateSite, name)
 	}
 
-	err = json.Unmarshal(configContents, &c)
-	if err != nil {
-		log.Fatalf("Could not unmarshal config file contents: %s", err.Error())
+	// invalid fields are reported before prompting
+	si := io.SiteInfo{Name: name}
+	if err = v.ApplyDetails(&si, opts.Details); err != nil {
+		return err
 	}
 
-	masterPub := c.MasterPubKey
-
 	// prompt for username
-	username := io.Prompt(fmt.Sprintf("Enter your username for %s: ", name))
+	if opts.Username != nil {
+		si.Username = *opts.Username
+	} else if si.Username, err = io.Prompt(fmt.Sprintf("Enter your username for %s: ", name)); err != nil {
+		return err
+	}
 
 	// prompt for password
-	pass, err := io.PromptPass(fmt.Sprintf("Please enter your password for %s", name))
-	if err != nil {
-		log.Fatalf("Could not read password: %s", err.Error())
+	var pass string
+	switch {
+	case opts.Password != nil:
+		pass = *opts.Password
+	case opts.Generate != nil:
+		pass, err = opts.Generate.New()
+	default:
+		pass, err = io.PromptPass(fmt.Sprintf("Please enter your password for %s", name))
 	}
-
-	passSealed, err := pc.BoxSeal([]byte(pass), &masterPub, priv)
 	if err != nil {
-		log.Fatalf("Could not seal new site password: %s", err.Error())
-	}
-
-	si := io.SiteInfo{
-		PubKey:   *pub,
-		Name:     name,
-		Username: username,
+		return fmt.Errorf("could not read password: %w", err)
 	}
 
-	err = si.AddFile(passSealed, name)
-
+	err = v.Add(si, pass)
 	if err != nil {
-		log.Fatalf("Could not save site info to file: %s", err.Error())
-	} else {
-		fmt.Printf("Successfully added password to %s", name)
+		return fmt.Errorf("could not save site info to file: %w", err)
 	}
-}
-
-// Handling whether vault exist
-func HandleVaultExist() {
-	if vf, err := io.VaultExists(); err != nil {
-		log.Fatalf("Could not get vault: %s", err.Error())
-	} else if vf == false {
-		log.Fatalf("Vault does not exist. Run mypass init")
+	fmt.Printf("Successfully added password to %s\n", name)
+	if opts.Generate != nil {
+		return opts.Generate.Deliver(pass)
 	}
+	return nil
 }
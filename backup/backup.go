@@ -0,0 +1,92 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/session"
+	"github.com/jeremyphua/mypass/vault"
+)
+
+// Create writes a checksummed tarball of masterpass, sites.json and the vault folder to file,
+// which must not exist yet. The passwords stay encrypted, the master password is not needed.
+func Create(file string) error {
+	if err := io.RequireVault(); err != nil {
+		return err
+	}
+	d, err := io.GetPassDir()
+	if err != nil {
+		return fmt.Errorf("could not get pass dir: %w", err)
+	}
+	// open the vault first so that an interrupted operation is rolled back before the snapshot
+	s := session.Storage(d)
+	if _, err = session.Open(s); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("could not create backup file: %w", err)
+	}
+	m, err := vault.WriteSnapshot(s, f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(file)
+		return fmt.Errorf("could not write backup file: %w", err)
+	}
+	fmt.Printf("Backed up %s to %s\n", describeSites(m), file)
+	return nil
+}
+
+// The sites of an encrypted index are only known with the master password, they are not counted
+func describeSites(m *vault.Manifest) string {
+	if m.EncryptedIndex {
+		return "the vault with an encrypted index"
+	}
+	if m.SitesUnknown {
+		return "an unknown number of sites"
+	}
+	return fmt.Sprintf("%d sites", m.Sites)
+}
+
+// Restore checks the tarball at file against its checksums and restores it to dir,
+// or to the pass dir when dir is empty. An existing vault is only replaced when force is set.
+func Restore(file, dir string, force bool) error {
+	if dir == "" {
+		d, err := io.GetPassDir()
+		if err != nil {
+			return fmt.Errorf("could not get pass dir: %w", err)
+		}
+		dir = d
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("could not open backup file: %w", err)
+	}
+	defer f.Close()
+	snap, err := vault.ReadSnapshot(f)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("could not create vault directory: %w", err)
+	}
+	s := session.Storage(dir)
+	if err = snap.Restore(s, force); err != nil {
+		return err
+	}
+	// vaults backed up in an older format are migrated as they are opened
+	if _, err = session.Open(s); err != nil {
+		return err
+	}
+	fmt.Printf("Restored %s backed up on %s to %s\n", describeSites(&snap.Manifest), snap.Manifest.Created.Local().Format("2006-01-02 15:04:05"), dir)
+	return nil
+}
@@ -0,0 +1,96 @@
+package clip
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	goio "io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ClearCommand is the hidden mypass command run by the background helper
+const ClearCommand = "clipboard-clear"
+
+// DefaultClearAfter is how long a copied password stays on the clipboard
+const DefaultClearAfter = 45 * time.Second
+
+// Copy writes value to the clipboard and, unless clearAfter is 0, starts a background
+// mypass process that clears the clipboard after clearAfter if it still holds value.
+// name tells what was copied in the messages, such as Password or the name of a field.
+func Copy(c Clipboard, name, value string, clearAfter time.Duration) error {
+	if err := c.WriteAll(value); err != nil {
+		return fmt.Errorf("could not copy %s to clipboard: %w", name, err)
+	}
+	if clearAfter <= 0 {
+		fmt.Printf("%s copied to clipboard\n", name)
+		return nil
+	}
+	if err := startClearHelper(hash(value), clearAfter); err != nil {
+		return fmt.Errorf("%s copied to clipboard but it will not be cleared: %w", name, err)
+	}
+	fmt.Printf("%s copied to clipboard, it will be cleared in %s\n", name, clearAfter)
+	return nil
+}
+
+// ClearIfUnchanged waits for clearAfter, then empties the clipboard if its contents
+// still hash to valueHash. Anything copied in the meantime is left alone.
+func ClearIfUnchanged(c Clipboard, valueHash string, clearAfter time.Duration) error {
+	time.Sleep(clearAfter)
+	current, err := c.ReadAll()
+	if err != nil {
+		return fmt.Errorf("could not read clipboard: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(hash(current)), []byte(valueHash)) != 1 {
+		return nil
+	}
+	return c.WriteAll("")
+}
+
+// ReadHash reads the hash handed to the helper on its standard input
+func ReadHash(r goio.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != goio.EOF {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// Only the hash of the password is handed to the helper, so it never appears
+// in its arguments or memory
+func startClearHelper(valueHash string, clearAfter time.Duration) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	// the hash is small enough to sit in the pipe buffer, so it is written before the helper
+	// starts and stays readable after this process exits
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if _, err = w.WriteString(valueHash + "\n"); err != nil {
+		w.Close()
+		return err
+	}
+	w.Close()
+
+	helper := exec.Command(self, ClearCommand, "--after", clearAfter.String())
+	helper.Stdin = r
+	helper.SysProcAttr = detached()
+	if err = helper.Start(); err != nil {
+		return err
+	}
+	// the helper outlives this process, don't wait for it
+	return helper.Process.Release()
+}
+
+func hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
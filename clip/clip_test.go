@@ -0,0 +1,88 @@
+package clip
+
+import (
+	"errors"
+	goio "io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCopy(t *testing.T) {
+	c := &MemClipboard{}
+	out := captureStdout(t, func() {
+		if err := Copy(c, "pin", "1234", 0); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if c.Text != "1234" {
+		t.Errorf("clipboard holds %q, want 1234", c.Text)
+	}
+	if out != "pin copied to clipboard\n" {
+		t.Errorf("Copy() printed %q, want the name of the field", out)
+	}
+
+	failed := errors.New("no clipboard")
+	c.Err = failed
+	if err := Copy(c, "Password", "secret", 0); !errors.Is(err, failed) {
+		t.Fatalf("Copy() = %v, want %v", err, failed)
+	}
+}
+
+func TestClearIfUnchanged(t *testing.T) {
+	tests := []struct {
+		name string
+		// copied by the user after the password
+		copied string
+		want   string
+	}{
+		{name: "unchanged", want: ""},
+		{name: "changed", copied: "something else", want: "something else"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &MemClipboard{Text: "secret"}
+			if tt.copied != "" {
+				c.Text = tt.copied
+			}
+			if err := ClearIfUnchanged(c, hash("secret"), 0); err != nil {
+				t.Fatal(err)
+			}
+			if c.Text != tt.want {
+				t.Fatalf("clipboard holds %q, want %q", c.Text, tt.want)
+			}
+		})
+	}
+
+	failed := errors.New("no clipboard")
+	if err := ClearIfUnchanged(&MemClipboard{Err: failed}, hash("secret"), 0); !errors.Is(err, failed) {
+		t.Fatalf("ClearIfUnchanged() = %v, want %v", err, failed)
+	}
+}
+
+func TestReadHash(t *testing.T) {
+	for _, input := range []string{hash("secret") + "\n", hash("secret")} {
+		if got, err := ReadHash(strings.NewReader(input)); err != nil || got != hash("secret") {
+			t.Errorf("ReadHash(%q) = %q, %v", input, got, err)
+		}
+	}
+}
+
+// Run fn and return what it printed
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	fn()
+	w.Close()
+	out, err := goio.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
@@ -0,0 +1,22 @@
+package clip
+
+import "github.com/atotto/clipboard"
+
+// Clipboard reads and writes text on a clipboard
+type Clipboard interface {
+	ReadAll() (string, error)
+	WriteAll(text string) error
+}
+
+// SystemClipboard is the clipboard of the desktop session, replace it with a MemClipboard in tests
+var SystemClipboard Clipboard = systemClipboard{}
+
+type systemClipboard struct{}
+
+func (systemClipboard) ReadAll() (string, error) {
+	return clipboard.ReadAll()
+}
+
+func (systemClipboard) WriteAll(text string) error {
+	return clipboard.WriteAll(text)
+}
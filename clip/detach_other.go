@@ -0,0 +1,9 @@
+//go:build plan9 || js
+
+package clip
+
+import "syscall"
+
+func detached() *syscall.SysProcAttr {
+	return nil
+}
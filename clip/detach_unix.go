@@ -0,0 +1,10 @@
+//go:build !windows && !plan9 && !js
+
+package clip
+
+import "syscall"
+
+// Run the helper in its own session so it survives the terminal being closed
+func detached() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,12 @@
+//go:build windows
+
+package clip
+
+import "syscall"
+
+// DETACHED_PROCESS, the helper does not keep the console open
+const detachedProcess = 0x00000008
+
+func detached() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP}
+}
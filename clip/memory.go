@@ -0,0 +1,20 @@
+package clip
+
+// MemClipboard is a clipboard in memory, meant for tests.
+// Every call fails with Err once it is set.
+type MemClipboard struct {
+	Text string
+	Err  error
+}
+
+func (c *MemClipboard) ReadAll() (string, error) {
+	return c.Text, c.Err
+}
+
+func (c *MemClipboard) WriteAll(text string) error {
+	if c.Err != nil {
+		return c.Err
+	}
+	c.Text = text
+	return nil
+}
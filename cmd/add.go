@@ -5,11 +5,12 @@ package cmd
 
 import (
 	"github.com/jeremyphua/mypass/add"
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/session"
 	"github.com/spf13/cobra"
 )
 
-var username string
-var password string
+var addGenerate generateFlags
 
 // addCmd represents the add command
 var addCmd = &cobra.Command{
@@ -18,12 +19,35 @@ var addCmd = &cobra.Command{
 	Short:   "Add a password to your vault",
 	Long:    `Add a site to your password store. This site can optionally be a part of a group by prepending a group name and slash to the site name. Will prompt for confirmation when a site path is not unique.`,
 	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		siteName := args[0]
-		add.AddPassword(siteName)
+		gen, err := addGenerate.options(cmd)
+		if err != nil {
+			return err
+		}
+		if err = io.RequireVault(); err != nil {
+			return err
+		}
+		v, err := session.OpenDefault()
+		if err != nil {
+			return err
+		}
+		// adding to an encrypted index asks for the master password
+		user, pass, err := credentials(cmd, gen, v.IndexEncrypted())
+		if err != nil {
+			return err
+		}
+		d, err := details(cmd)
+		if err != nil {
+			return err
+		}
+		return add.AddPassword(v, siteName, add.Options{Username: user, Password: pass, Generate: gen, Details: d})
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(addCmd)
+	addGenerate.register(addCmd)
+	registerCredentialFlags(addCmd)
+	registerDetailFlags(addCmd)
 }
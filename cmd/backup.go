@@ -0,0 +1,30 @@
+/*
+Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
+*/
+package cmd
+
+import (
+	"github.com/jeremyphua/mypass/backup"
+	"github.com/spf13/cobra"
+)
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:     "backup <file>",
+	Example: "mypass backup mypass-2022-06-01.tar.gz",
+	Short:   "Back up the whole vault to a tarball",
+	Long: `Write masterpass, sites.json and the vault folder to a new gzipped tarball, along with
+the SHA-256 of every file. The passwords stay encrypted, so no master password is needed,
+and the backup is restored with mypass restore.
+
+mypass also keeps rolling backups in the backups/auto folder of the vault, taken before
+delete, rename and rotate, see --auto-backups.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return backup.Create(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+}
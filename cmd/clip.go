@@ -0,0 +1,53 @@
+/*
+Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
+*/
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/jeremyphua/mypass/clip"
+	"github.com/jeremyphua/mypass/show"
+	"github.com/spf13/cobra"
+)
+
+var clipClearAfter time.Duration
+var clipField string
+var helperClearAfter time.Duration
+
+// clipCmd represents the clip command
+var clipCmd = &cobra.Command{
+	Use:     "clip",
+	Example: "mypass clip money/ocbc",
+	Short:   "Copy the password of a site to the clipboard",
+	Long:    `Copy the password of a site to the clipboard, same as show --copy. The clipboard is cleared after --clear-after unless something else was copied in the meantime.`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := args[0]
+		return show.Site(path, clipField, 0, true, clipClearAfter, allowPrompts(false))
+	},
+}
+
+// clipboardClearCmd is the background helper started by clip.Copy
+var clipboardClearCmd = &cobra.Command{
+	Use:    clip.ClearCommand,
+	Short:  "Clear the clipboard if it still holds the copied password",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		valueHash, err := clip.ReadHash(os.Stdin)
+		if err != nil {
+			return err
+		}
+		return clip.ClearIfUnchanged(clip.SystemClipboard, valueHash, helperClearAfter)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(clipCmd)
+	rootCmd.AddCommand(clipboardClearCmd)
+	clipCmd.Flags().DurationVar(&clipClearAfter, "clear-after", clip.DefaultClearAfter, "Clear the copied password from the clipboard after this duration, 0 keeps it")
+	clipCmd.Flags().StringVar(&clipField, "field", "", "Copy this field instead of the password: username, url, notes or a custom field")
+	clipboardClearCmd.Flags().DurationVar(&helperClearAfter, "after", clip.DefaultClearAfter, "How long to wait before clearing the clipboard")
+}
@@ -8,17 +8,20 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var deleteYes bool
+
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
 	Use:   "delete",
 	Short: "Remove a specific site from the vault by specifying the site-path",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		siteName := args[0]
-		edit.DeleteSite(siteName)
+		return edit.DeleteSite(siteName, deleteYes, allowPrompts(deleteYes))
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(deleteCmd)
+	deleteCmd.Flags().BoolVarP(&deleteYes, "yes", "y", false, "Delete without asking for confirmation")
 }
@@ -9,18 +9,35 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var editGenerate generateFlags
+
 // editCmd represents the edit command
 var editCmd = &cobra.Command{
 	Use:     "edit",
 	Example: "mypass edit money/ocbc",
 	Short:   "Change the username or password of a site in the vault.",
 	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		siteName := args[0]
-		edit.EditInformation(siteName)
+		gen, err := editGenerate.options(cmd)
+		if err != nil {
+			return err
+		}
+		user, pass, err := credentials(cmd, gen, true)
+		if err != nil {
+			return err
+		}
+		d, err := details(cmd)
+		if err != nil {
+			return err
+		}
+		return edit.EditInformation(siteName, edit.Options{Username: user, Password: pass, Generate: gen, Details: d, Prompt: allowPrompts(false)})
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(editCmd)
+	editGenerate.register(editCmd)
+	registerCredentialFlags(editCmd)
+	registerDetailFlags(editCmd)
 }
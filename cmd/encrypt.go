@@ -0,0 +1,32 @@
+/*
+Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
+*/
+package cmd
+
+import (
+	"github.com/jeremyphua/mypass/encrypt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	decryptIndex              bool
+	encryptIndexRemoveBackups bool
+)
+
+// encryptIndexCmd represents the encrypt-index command
+var encryptIndexCmd = &cobra.Command{
+	Use:     "encrypt-index",
+	Example: "mypass encrypt-index\nmypass encrypt-index --remove-backups\nmypass encrypt-index --decrypt",
+	Short:   "Encrypt the site names and usernames of the vault",
+	Long:    `Migrate a vault to an encrypted index. sites.json is sealed with a key derived from the master key and the password files get opaque names, so that the sites and usernames in the vault can't be read without the master password. The backups in the vault directory still show the sites, use --remove-backups to remove them afterwards. Listing the vault then asks for the master password. Use --decrypt to go back to a plaintext index.`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return encrypt.EncryptIndex(!decryptIndex, encryptIndexRemoveBackups)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(encryptIndexCmd)
+	encryptIndexCmd.Flags().BoolVar(&decryptIndex, "decrypt", false, "Store the index in plaintext again")
+	encryptIndexCmd.Flags().BoolVar(&encryptIndexRemoveBackups, "remove-backups", false, "Remove the backups in the vault directory, which still show the sites")
+}
@@ -0,0 +1,59 @@
+/*
+Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
+*/
+package cmd
+
+import (
+	"errors"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/pc"
+	"github.com/jeremyphua/mypass/vault"
+)
+
+// Exit codes returned by mypass, one per error class
+const (
+	exitError                   = 1
+	exitVaultNotInitialized     = 3
+	exitVaultAlreadyInitialized = 4
+	exitSiteNotFound            = 5
+	exitDuplicateSite           = 6
+	exitWrongMasterPassword     = 7
+	exitVaultInUse              = 8
+	exitInvalidSiteName         = 9
+	exitInsecurePermissions     = 10
+	exitNewerVersion            = 11
+)
+
+// exitCode maps an error returned by a command to the exit code of its class
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, io.ErrVaultNotInitialized):
+		return exitVaultNotInitialized
+	case errors.Is(err, io.ErrVaultAlreadyInitialized):
+		return exitVaultAlreadyInitialized
+	case errors.Is(err, io.ErrSiteNotFound):
+		return exitSiteNotFound
+	case errors.Is(err, io.ErrDuplicateSite):
+		return exitDuplicateSite
+	case errors.Is(err, pc.ErrWrongMasterPassword):
+		return exitWrongMasterPassword
+	case errors.Is(err, vault.ErrInUse), errors.Is(err, vault.ErrVaultChanged):
+		return exitVaultInUse
+	case errors.Is(err, io.ErrInvalidSiteName):
+		return exitInvalidSiteName
+	case errors.Is(err, vault.ErrInsecurePermissions):
+		return exitInsecurePermissions
+	case errors.Is(err, vault.ErrNewerVersion):
+		return exitNewerVersion
+	}
+	return exitError
+}
+
+// hint returns advice printed after the error message, if any
+func hint(err error) string {
+	if errors.Is(err, vault.ErrInsecurePermissions) {
+		return "Run the command again with --fix-perms to restrict the permissions to your user"
+	}
+	return ""
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/pc"
+	"github.com/jeremyphua/mypass/vault"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{io.ErrVaultNotInitialized, exitVaultNotInitialized},
+		{io.ErrVaultAlreadyInitialized, exitVaultAlreadyInitialized},
+		{fmt.Errorf("%w: money/ocbc", io.ErrSiteNotFound), exitSiteNotFound},
+		{fmt.Errorf("%w: money/ocbc", io.ErrDuplicateSite), exitDuplicateSite},
+		{pc.ErrWrongMasterPassword, exitWrongMasterPassword},
+		{fmt.Errorf("%w by PID 42", vault.ErrInUse), exitVaultInUse},
+		{vault.ErrVaultChanged, exitVaultInUse},
+		{fmt.Errorf("%w: ../escape", io.ErrInvalidSiteName), exitInvalidSiteName},
+		{fmt.Errorf("could not open vault: %w", vault.ErrInsecurePermissions), exitInsecurePermissions},
+		{vault.ErrNewerVersion, exitNewerVersion},
+		{errors.New("disk full"), exitError},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%q) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
+*/
+package cmd
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jeremyphua/mypass/export"
+	"github.com/spf13/cobra"
+)
+
+var exportPlaintext bool
+var exportFormat string
+var iUnderstand bool
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:     "export <file>",
+	Example: "mypass export backup.mypass\nmypass export --plaintext --format json --i-understand bitwarden.json",
+	Short:   "Export every site, decrypted, to a file",
+	Long: `Export the passwords, notes, fields and previous passwords of every site to a new file.
+
+By default the file is an archive encrypted with a passphrase you choose, which
+mypass import --format mypass-export reads back.
+
+With --plaintext --i-understand the file is an unencrypted CSV or JSON file in the
+Bitwarden format, which most password managers import. Anyone who can read it has
+every password, delete it once it has been imported.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flags().Changed("format") && !exportPlaintext {
+			return errors.New("--format requires --plaintext, the encrypted archive has a single format")
+		}
+		export.SetPassphraseFile(passphraseFile)
+		return export.Export(args[0], export.Options{
+			Plaintext: exportPlaintext,
+			Format:    exportFormat,
+			Confirmed: iUnderstand,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().BoolVar(&exportPlaintext, "plaintext", false, "Write an unencrypted file instead of an encrypted archive")
+	exportCmd.Flags().StringVar(&exportFormat, "format", export.CSV, "Format of a plaintext export: "+strings.Join(export.PlaintextFormats, ", "))
+	exportCmd.Flags().BoolVar(&iUnderstand, "i-understand", false, "Confirm that a plaintext export writes every password unencrypted")
+	exportCmd.Flags().StringVar(&passphraseFile, "passphrase-file", "", "Read the passphrase of the archive from the first line of this file")
+}
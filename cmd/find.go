@@ -0,0 +1,36 @@
+/*
+Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
+*/
+package cmd
+
+import (
+	"github.com/jeremyphua/mypass/find"
+	"github.com/spf13/cobra"
+)
+
+var findLimit int
+
+// findCmd represents the find command
+var findCmd = &cobra.Command{
+	Use:     "find <query>",
+	Example: "mypass find ocbc\nmypass find 'money/*'\nmypass find gthub",
+	Short:   "Search the sites by name, username, URL and fields",
+	Long: `List the sites matching a query, best match first, ignoring case.
+
+A query containing * ? or [ is a glob matched against the whole site name, the name
+within its group, and the username. Any other query matches site names and usernames
+as a substring, fuzzily as letters in order (ocbc matches money/ocbc-bank), or with a
+few typos, and the URL and plain custom fields as a substring.
+
+show, edit, delete and rename suggest the closest sites when no site has the exact name
+given, and let you choose one when run in a terminal.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return find.Sites(args[0], findLimit)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(findCmd)
+	findCmd.Flags().IntVarP(&findLimit, "limit", "n", 20, "Print at most this many sites, 0 prints them all")
+}
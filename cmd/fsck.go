@@ -0,0 +1,42 @@
+/*
+Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
+*/
+package cmd
+
+import (
+	"github.com/jeremyphua/mypass/fsck"
+	"github.com/spf13/cobra"
+)
+
+var fsckDecrypt bool
+var fsckRepair bool
+
+// fsckCmd represents the fsck command
+var fsckCmd = &cobra.Command{
+	Use:     "fsck",
+	Example: "mypass fsck\nmypass fsck --decrypt --repair",
+	Short:   "Check the vault for problems",
+	Long: `Check that sites.json and the password files of the vault folder agree. Reported are:
+
+  invalid name   a site name that is not a valid site path, made of segments separated by
+                 slashes, each containing only letters, digits and - _ . @ +
+  duplicate      a site with the name of an earlier site
+  dangling       a site without a password file
+  orphan         a password file that no site points to
+  empty folder   a folder of the vault folder without password files
+  undecryptable  a password or previous password that does not decrypt, checked with --decrypt
+
+--repair takes a rolling backup, then removes the duplicates, dangling sites, orphans and
+empty folders. Sites with invalid names keep working, rename them with mypass rename as suggested.
+Undecryptable passwords are left for you to restore from a backup.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return fsck.Check(fsckDecrypt, fsckRepair)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fsckCmd)
+	fsckCmd.Flags().BoolVar(&fsckDecrypt, "decrypt", false, "Unlock the vault and check that every password decrypts")
+	fsckCmd.Flags().BoolVar(&fsckRepair, "repair", false, "Fix the problems that can be fixed, after a backup of the vault")
+}
@@ -6,24 +6,35 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
-	"github.com/jeremyphua/mypass/generate"
 	"github.com/spf13/cobra"
 )
 
+var generatePolicy policyFlags
+
 // generateCmd represents the generate command
 var generateCmd = &cobra.Command{
 	Use:     "generate",
 	Short:   "Generate a secure password",
-	Example: "mypass generator",
-	Long:    `Prints a randomly generated password. The default length is 20.`,
+	Example: "mypass generate --length 32 --exclude-ambiguous\nmypass generate --words 6 --capitalize --digit",
+	Long:    `Prints a randomly generated password. The default length is 20, with at least one uppercase letter, lowercase letter, digit and symbol. With --words, prints a passphrase of words from the EFF long wordlist along with its entropy.`,
 	Args:    cobra.NoArgs,
-	Run: func(cmd *cobra.Command, args []string) {
-		password := generate.Password()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		password, entropy, err := generatePolicy.generate(cmd)
+		if err != nil {
+			return err
+		}
 		fmt.Println(password)
+		if entropy > 0 {
+			// keep stdout to the passphrase alone so it can be piped
+			fmt.Fprintf(os.Stderr, "Entropy: %.1f bits\n", entropy)
+		}
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(generateCmd)
+	generatePolicy.register(generateCmd)
 }
@@ -0,0 +1,40 @@
+/*
+Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
+*/
+package cmd
+
+import (
+	"errors"
+
+	"github.com/jeremyphua/mypass/history"
+	"github.com/spf13/cobra"
+)
+
+var historyKeep int
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:     "history",
+	Example: "mypass history money/ocbc\nmypass history --keep 5",
+	Short:   "List the previous passwords of a site",
+	Long:    `List the previous passwords of a site with the time they were replaced, masked. Reveal one with mypass show <site> --revision N. Use --keep to change how many previous passwords are kept per site.`,
+	Args:    cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flags().Changed("keep") {
+			if err := history.SetLimit(historyKeep); err != nil {
+				return err
+			}
+		} else if len(args) == 0 {
+			return errors.New("specify a site or --keep")
+		}
+		if len(args) == 1 {
+			return history.List(args[0])
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().IntVar(&historyKeep, "keep", 0, "Number of previous passwords to keep per site, 0 disables the history")
+}
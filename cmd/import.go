@@ -0,0 +1,60 @@
+/*
+Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jeremyphua/mypass/export"
+	"github.com/jeremyphua/mypass/importer"
+	"github.com/spf13/cobra"
+)
+
+var importFormat string
+var importOnDuplicate string
+var importGroup string
+var importDryRun bool
+var passphraseFile string
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:     "import [path]",
+	Example: "mypass import --format bitwarden-json bitwarden_export.json\nmypass import --format pass --group old --dry-run",
+	Short:   "Import sites from another password manager",
+	Long: fmt.Sprintf(`Import the sites of an export file or password store of another password manager.
+Folders become groups, passwords are sealed like mypass add does.
+
+Formats: %s.
+The passgo and pass formats read ~/.passgo and ~/.password-store when no path is given,
+passgo asks for its master password and pass decrypts through gpg.
+mypass-export reads an encrypted archive written by mypass export.
+
+Sites whose name is already taken are skipped, overwritten or imported under a new name
+such as site-2 depending on --on-duplicate. An overwritten site only keeps its previous passwords
+and creation time, its username, URL, notes and fields are those of the import.`, strings.Join(importer.FormatNames(), ", ")),
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		export.SetPassphraseFile(passphraseFile)
+		source := ""
+		if len(args) == 1 {
+			source = args[0]
+		}
+		return importer.Import(importFormat, source, importer.Options{
+			OnDuplicate: importOnDuplicate,
+			Group:       importGroup,
+			DryRun:      importDryRun,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVar(&importFormat, "format", "", "Format of the import: "+strings.Join(importer.FormatNames(), ", "))
+	importCmd.MarkFlagRequired("format")
+	importCmd.Flags().StringVar(&importOnDuplicate, "on-duplicate", importer.Skip, "What to do with sites that already exist: "+strings.Join(importer.Strategies, ", "))
+	importCmd.Flags().StringVar(&importGroup, "group", "", "Import every site below this group")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Only print what would be imported")
+	importCmd.Flags().StringVar(&passphraseFile, "passphrase-file", "", "Read the passphrase of a mypass-export archive from the first line of this file")
+}
@@ -9,16 +9,20 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var initEncryptIndex bool
+
 // initCmd represents the init command
 var initCmd = &cobra.Command{
-	Use:   "init",
-	Short: "Initialize your pass vault",
-	Long:  `Initialize your pass vault and generate your master password`,
-	Run: func(cmd *cobra.Command, args []string) {
-		initialize.Init()
+	Use:     "init",
+	Example: "mypass init --vault-dir ~/work-vault",
+	Short:   "Initialize your pass vault",
+	Long:    `Initialize your pass vault and generate your master password. The vault is created in $HOME/.mypass unless --vault-dir or the MYPASS_DIR environment variable points somewhere else.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return initialize.Init(initEncryptIndex)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().BoolVar(&initEncryptIndex, "encrypt-index", false, "Encrypt the site names and usernames too, see mypass encrypt-index")
 }
@@ -0,0 +1,103 @@
+/*
+Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jeremyphua/mypass/generate"
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/vault"
+	"github.com/spf13/cobra"
+)
+
+// Non-interactive credentials of add and edit
+var username string
+var passwordStdin bool
+
+func registerCredentialFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&username, "username", "u", "", "Username of the site instead of prompting for it")
+	cmd.Flags().BoolVar(&passwordStdin, "password-stdin", false, "Read the password of the site from stdin instead of prompting for it")
+}
+
+// credentials returns the username and password given on cmd, nil when they should be prompted for.
+// needsUnlock tells whether the command asks for the master password, which then can't come from stdin.
+func credentials(cmd *cobra.Command, gen *generate.Options, needsUnlock bool) (user, pass *string, err error) {
+	if cmd.Flags().Changed("username") {
+		user = &username
+	}
+	if !passwordStdin {
+		return user, nil, nil
+	}
+	if gen != nil {
+		return nil, nil, errors.New("--password-stdin cannot be used with --generate")
+	}
+	if user == nil && cmd.Name() == "add" {
+		return nil, nil, errors.New("--password-stdin requires --username")
+	}
+	if needsUnlock && !io.HasMasterPasswordSource() {
+		return nil, nil, fmt.Errorf("--password-stdin requires --master-password-file or %s", io.MasterPasswordFDEnv)
+	}
+	password, err := io.ReadPassStdin()
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, &password, nil
+}
+
+// allowPrompts tells whether a command may ask questions a script cannot answer, such as
+// choosing among the closest sites: only on a terminal, and not when the master password
+// comes from a file or descriptor or when --yes was passed.
+func allowPrompts(yes bool) bool {
+	return io.IsInteractive() && !io.HasMasterPasswordSource() && !yes
+}
+
+// URL, notes and custom fields of add and edit
+var siteURL string
+var notes string
+var fields []string
+var secretFields []string
+var removeFields []string
+
+func registerDetailFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&siteURL, "url", "", "URL of the site")
+	cmd.Flags().StringVar(&notes, "notes", "", "Free-form notes of the site, stored encrypted")
+	cmd.Flags().StringArrayVar(&fields, "field", nil, "Set a custom field as key=value, can be repeated")
+	cmd.Flags().StringArrayVar(&secretFields, "secret-field", nil, "Set an encrypted custom field as key=value, or key to prompt for its value, can be repeated")
+	if cmd.Name() == "edit" {
+		cmd.Flags().StringArrayVar(&removeFields, "remove-field", nil, "Remove a custom field, can be repeated")
+	}
+}
+
+// details returns the URL, notes and custom fields given on cmd
+func details(cmd *cobra.Command) (vault.Details, error) {
+	var d vault.Details
+	if cmd.Flags().Changed("url") {
+		d.URL = &siteURL
+	}
+	if cmd.Flags().Changed("notes") {
+		d.Notes = &notes
+	}
+	for _, field := range fields {
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return d, fmt.Errorf("--field %s is not of the form key=value", field)
+		}
+		d.Fields = append(d.Fields, vault.FieldInput{Name: name, Value: value})
+	}
+	for _, field := range secretFields {
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			var err error
+			if value, err = io.PromptPass(fmt.Sprintf("Enter the value of %s", name)); err != nil {
+				return d, fmt.Errorf("could not read value of %s: %w", name, err)
+			}
+		}
+		d.Fields = append(d.Fields, vault.FieldInput{Name: name, Value: value, Secret: true})
+	}
+	d.Remove = removeFields
+	return d, nil
+}
@@ -0,0 +1,28 @@
+/*
+Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
+*/
+package cmd
+
+import (
+	"github.com/jeremyphua/mypass/passwd"
+	"github.com/spf13/cobra"
+)
+
+var passwdRemoveBackups bool
+
+// passwdCmd represents the passwd command
+var passwdCmd = &cobra.Command{
+	Use:     "passwd",
+	Example: "mypass passwd\nmypass passwd --remove-backups",
+	Short:   "Change your master password",
+	Long:    `Change the master password chosen in mypass init. The master private key is re-encrypted with the new password, the passwords stored in the vault are left untouched. The backups in the vault directory still open with the old password, use --remove-backups to remove them afterwards.`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return passwd.ChangeMasterPassword(passwdRemoveBackups)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(passwdCmd)
+	passwdCmd.Flags().BoolVar(&passwdRemoveBackups, "remove-backups", false, "Remove the backups in the vault directory, sealed with the old master password")
+}
@@ -0,0 +1,153 @@
+/*
+Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jeremyphua/mypass/clip"
+	"github.com/jeremyphua/mypass/generate"
+	"github.com/jeremyphua/mypass/pc"
+	"github.com/spf13/cobra"
+)
+
+// policyFlags are the password generator options shared by the commands that generate passwords
+type policyFlags struct {
+	length           int
+	noSymbols        bool
+	symbols          string
+	excludeAmbiguous bool
+	minUpper         int
+	minLower         int
+	minDigit         int
+	minSymbol        int
+
+	words      int
+	separator  string
+	capitalize bool
+	digit      bool
+}
+
+func (f *policyFlags) register(cmd *cobra.Command) {
+	d := pc.DefaultPolicy()
+	cmd.Flags().IntVar(&f.length, "length", d.Length, "Length of the generated password")
+	cmd.Flags().BoolVar(&f.noSymbols, "no-symbols", false, "Only use letters and digits")
+	cmd.Flags().StringVar(&f.symbols, "symbols", d.Symbols, "Symbols allowed in the generated password")
+	cmd.Flags().BoolVar(&f.excludeAmbiguous, "exclude-ambiguous", false, "Leave out characters that look alike, such as 0 and O")
+	cmd.Flags().IntVar(&f.minUpper, "min-upper", d.MinUpper, "Minimum number of uppercase letters")
+	cmd.Flags().IntVar(&f.minLower, "min-lower", d.MinLower, "Minimum number of lowercase letters")
+	cmd.Flags().IntVar(&f.minDigit, "min-digit", d.MinDigit, "Minimum number of digits")
+	cmd.Flags().IntVar(&f.minSymbol, "min-symbol", d.MinSymbol, "Minimum number of symbols")
+
+	dp := pc.DefaultPassphrasePolicy()
+	cmd.Flags().IntVar(&f.words, "words", 0, "Generate a passphrase of this many words from the EFF long wordlist instead")
+	cmd.Flags().StringVar(&f.separator, "separator", dp.Separator, "Separator between the words of a passphrase")
+	cmd.Flags().BoolVar(&f.capitalize, "capitalize", false, "Capitalize every word of a passphrase")
+	cmd.Flags().BoolVar(&f.digit, "digit", false, "Add a random digit to a word of a passphrase")
+}
+
+// passwordFlags only apply to character passwords, passphraseFlags only to --words
+var (
+	passwordFlags   = []string{"length", "no-symbols", "symbols", "exclude-ambiguous", "min-upper", "min-lower", "min-digit", "min-symbol"}
+	passphraseFlags = []string{"separator", "capitalize", "digit"}
+)
+
+// generate returns a passphrase when --words is given and a password otherwise.
+// The entropy is only known for passphrases and is 0 for passwords.
+func (f *policyFlags) generate(cmd *cobra.Command) (secret string, entropy float64, err error) {
+	if f.words == 0 {
+		if name := changedFlag(cmd, passphraseFlags); name != "" {
+			return "", 0, fmt.Errorf("--%s can only be used with --words", name)
+		}
+		p, err := f.policy(cmd)
+		if err != nil {
+			return "", 0, err
+		}
+		secret, err = generate.Password(p)
+		return secret, 0, err
+	}
+	if name := changedFlag(cmd, passwordFlags); name != "" {
+		return "", 0, fmt.Errorf("--%s cannot be used with --words", name)
+	}
+	return generate.Passphrase(pc.PassphrasePolicy{
+		Words:      f.words,
+		Separator:  f.separator,
+		Capitalize: f.capitalize,
+		Digit:      f.digit,
+	})
+}
+
+// first flag of names given on the command line
+func changedFlag(cmd *cobra.Command, names []string) string {
+	for _, name := range names {
+		if cmd.Flags().Changed(name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// policy builds the generator policy from the flags given on cmd
+func (f *policyFlags) policy(cmd *cobra.Command) (pc.Policy, error) {
+	p := pc.Policy{
+		Length:           f.length,
+		Symbols:          f.symbols,
+		ExcludeAmbiguous: f.excludeAmbiguous,
+		MinUpper:         f.minUpper,
+		MinLower:         f.minLower,
+		MinDigit:         f.minDigit,
+		MinSymbol:        f.minSymbol,
+	}
+	if f.noSymbols {
+		if cmd.Flags().Changed("symbols") {
+			return p, errors.New("--no-symbols and --symbols cannot be used together")
+		}
+		p.Symbols = ""
+		// the default minimum of one symbol does not apply without symbols
+		if !cmd.Flags().Changed("min-symbol") {
+			p.MinSymbol = 0
+		}
+	}
+	return p, nil
+}
+
+// generateFlags add --generate and the generator options to add and edit
+type generateFlags struct {
+	policyFlags
+	generate   bool
+	copy       bool
+	clearAfter time.Duration
+}
+
+func (f *generateFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&f.generate, "generate", false, "Generate the password instead of prompting for it, it is never printed")
+	cmd.Flags().BoolVarP(&f.copy, "copy", "c", false, "Copy the generated password to the clipboard")
+	cmd.Flags().DurationVar(&f.clearAfter, "clear-after", clip.DefaultClearAfter, "Clear the copied password from the clipboard after this duration, 0 keeps it")
+	f.policyFlags.register(cmd)
+}
+
+// options returns nil unless --generate was given
+func (f *generateFlags) options(cmd *cobra.Command) (*generate.Options, error) {
+	if !f.generate {
+		names := append([]string{"words", "copy", "clear-after"}, passwordFlags...)
+		if name := changedFlag(cmd, append(names, passphraseFlags...)); name != "" {
+			return nil, fmt.Errorf("--%s can only be used with --generate", name)
+		}
+		return nil, nil
+	}
+	// catch invalid generator options before prompting for anything
+	if _, _, err := f.policyFlags.generate(cmd); err != nil {
+		return nil, err
+	}
+	return &generate.Options{
+		New: func() (string, error) {
+			password, _, err := f.policyFlags.generate(cmd)
+			return password, err
+		},
+		Copy:       f.copy,
+		ClearAfter: f.clearAfter,
+	}, nil
+}
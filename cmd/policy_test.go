@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jeremyphua/mypass/pc"
+	"github.com/spf13/cobra"
+)
+
+// Parse args with the flags of add and edit
+func parseGenerateFlags(t *testing.T, args ...string) (*generateFlags, *cobra.Command) {
+	t.Helper()
+	var f generateFlags
+	cmd := &cobra.Command{Use: "add"}
+	f.register(cmd)
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+	return &f, cmd
+}
+
+func TestGenerateOptions(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantNil bool
+		wantErr string
+		check   func(password string) bool
+	}{
+		{args: nil, wantNil: true},
+		{args: []string{"--words", "4"}, wantErr: "--words can only be used with --generate"},
+		{args: []string{"--copy"}, wantErr: "--copy can only be used with --generate"},
+		{args: []string{"--generate"}, check: func(p string) bool { return len(p) == pc.DefaultPolicy().Length }},
+		{args: []string{"--generate", "--length", "32", "--no-symbols"}, check: func(p string) bool {
+			return len(p) == 32 && !strings.ContainsAny(p, pc.DefaultPolicy().Symbols)
+		}},
+		{args: []string{"--generate", "--words", "5", "--separator", "."}, check: func(p string) bool {
+			return len(strings.Split(p, ".")) == 5
+		}},
+		{args: []string{"--generate", "--words", "5", "--length", "10"}, wantErr: "--length cannot be used with --words"},
+		{args: []string{"--generate", "--capitalize"}, wantErr: "--capitalize can only be used with --words"},
+		{args: []string{"--generate", "--length", "2", "--min-upper", "3"}, wantErr: "cannot be satisfied"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			f, cmd := parseGenerateFlags(t, tt.args...)
+			opts, err := f.options(cmd)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("options() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantNil {
+				if opts != nil {
+					t.Fatal("options() without --generate are not nil")
+				}
+				return
+			}
+			password, err := opts.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(password) {
+				t.Fatalf("generated %q does not follow %v", password, tt.args)
+			}
+		})
+	}
+}
+
+func TestGenerateOptionsCopy(t *testing.T) {
+	f, cmd := parseGenerateFlags(t, "--generate", "-c", "--clear-after", "10s")
+	opts, err := f.options(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !opts.Copy || opts.ClearAfter.String() != "10s" {
+		t.Fatalf("options() copy %v after %s, want true after 10s", opts.Copy, opts.ClearAfter)
+	}
+}
@@ -9,18 +9,25 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var renameYes bool
+
 // renameCmd represents the rename command
 var renameCmd = &cobra.Command{
 	Use:     "rename",
 	Short:   "Rename an entry in the password vault",
-	Example: "mypass rename money/ocbc",
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	Example: "mypass rename money/ocbc\nmypass rename money/ocbc money/ocbc-savings",
+	Args:    cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		site := args[0]
-		edit.Rename(site)
+		newSite := ""
+		if len(args) == 2 {
+			newSite = args[1]
+		}
+		return edit.Rename(site, newSite, renameYes, allowPrompts(renameYes))
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(renameCmd)
+	renameCmd.Flags().BoolVarP(&renameYes, "yes", "y", false, "Rename without asking for confirmation")
 }
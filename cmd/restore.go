@@ -0,0 +1,33 @@
+/*
+Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
+*/
+package cmd
+
+import (
+	"github.com/jeremyphua/mypass/backup"
+	"github.com/spf13/cobra"
+)
+
+var restoreForce bool
+var restoreTo string
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:     "restore <file>",
+	Example: "mypass restore mypass-2022-06-01.tar.gz\nmypass restore mypass-2022-06-01.tar.gz --to /tmp/old-vault",
+	Short:   "Restore the vault from a backup tarball",
+	Long: `Check a tarball written by mypass backup against its checksums and restore the vault from it.
+
+An existing vault is only replaced with --force, after a rolling backup of it is taken.
+--to restores to another directory, which can then be opened with --vault-dir.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return backup.Restore(args[0], restoreTo, restoreForce)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+	restoreCmd.Flags().BoolVar(&restoreForce, "force", false, "Replace the existing vault")
+	restoreCmd.Flags().StringVar(&restoreTo, "to", "", "Restore to this directory instead of the vault directory")
+}
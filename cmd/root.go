@@ -4,24 +4,46 @@ Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/session"
 	"github.com/jeremyphua/mypass/show"
+	"github.com/jeremyphua/mypass/vault"
 	"github.com/spf13/cobra"
 )
 
+var vaultDir string
+var lockTimeout time.Duration
+var fixPerms bool
+var masterPasswordFile string
+var autoBackups int
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "mypass",
 	Short: "A tool to manage your password",
 	Long:  `Prints the content of your vault. If you have not initialized your vault, please run the init subcommand to get started.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	// Errors are printed by Execute so that every command reports them the same way
+	SilenceErrors: true,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// arguments and flags are valid at this point, don't print the usage for runtime errors
+		cmd.SilenceUsage = true
+		if vaultDir != "" {
+			io.SetPassDir(vaultDir)
+		}
+		if masterPasswordFile != "" {
+			io.SetMasterPasswordFile(masterPasswordFile)
+		}
+		session.SetStorageOptions(vault.FileOptions{LockTimeout: lockTimeout, FixPermissions: fixPerms, AutoBackups: autoBackups})
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if exists, _ := io.VaultExists(); exists {
-			show.ListAll()
-		} else {
-			cmd.Help()
+			return show.ListAll()
 		}
+		return cmd.Help()
 	},
 }
 
@@ -30,10 +52,19 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		if h := hint(err); h != "" {
+			fmt.Fprintln(os.Stderr, h)
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
 func init() {
-
+	defaults := vault.DefaultFileOptions()
+	rootCmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", defaults.LockTimeout, "How long to wait for another mypass process to release the vault")
+	rootCmd.PersistentFlags().BoolVar(&fixPerms, "fix-perms", defaults.FixPermissions, "Repair the permissions of vault files accessible by other users instead of refusing to open the vault")
+	rootCmd.PersistentFlags().StringVar(&masterPasswordFile, "master-password-file", "", "Read the master password from the first line of this file instead of prompting for it (or set $"+io.MasterPasswordFDEnv+" to a file descriptor)")
+	rootCmd.PersistentFlags().IntVar(&autoBackups, "auto-backups", defaults.AutoBackups, "How many rolling backups to keep in the backups/auto folder of the vault, taken before delete, rename and rotate, 0 turns them off")
+	rootCmd.PersistentFlags().StringVar(&vaultDir, "vault-dir", "", "Directory of the vault (default $"+io.PassDirEnv+" or $HOME/.mypass)")
 }
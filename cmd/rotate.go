@@ -0,0 +1,28 @@
+/*
+Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
+*/
+package cmd
+
+import (
+	"github.com/jeremyphua/mypass/rotate"
+	"github.com/spf13/cobra"
+)
+
+var rotateRemoveBackups bool
+
+// rotateCmd represents the rotate-keys command
+var rotateCmd = &cobra.Command{
+	Use:     "rotate-keys",
+	Example: "mypass rotate-keys\nmypass rotate-keys --remove-backups",
+	Short:   "Generate a new master key pair and re-encrypt every site",
+	Long:    `Generate a new master key pair and re-encrypt every password in the vault with it. Use this if you suspect that your master private key has leaked. The vault is only replaced once every site has been re-encrypted successfully. The backups in the vault directory hold the old key, use --remove-backups to remove them afterwards.`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rotate.RotateKeys(rotateRemoveBackups)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rotateCmd)
+	rotateCmd.Flags().BoolVar(&rotateRemoveBackups, "remove-backups", false, "Remove the backups in the vault directory, sealed with the old master key")
+}
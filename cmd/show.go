@@ -5,25 +5,41 @@ Copyright © 2022 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"errors"
+	"time"
+
+	"github.com/jeremyphua/mypass/clip"
 	"github.com/jeremyphua/mypass/show"
 	"github.com/spf13/cobra"
 )
 
 var copyPass bool
+var clearAfter time.Duration
+var showField string
+var showRevision int
 
 // showCmd represents the show command
 var showCmd = &cobra.Command{
 	Use:     "show",
-	Example: "mypass show money/ocbc",
+	Example: "mypass show money/ocbc\nmypass show money/ocbc --field pin",
 	Short:   "Print the password of a mypass site.",
 	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		path := args[0]
-		show.Site(path, copyPass)
+		if showRevision < 0 {
+			return errors.New("--revision must be 1 or more")
+		}
+		if showRevision > 0 && showField != "" {
+			return errors.New("--revision cannot be used with --field")
+		}
+		return show.Site(path, showField, showRevision, copyPass, clearAfter, allowPrompts(false))
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(showCmd)
 	showCmd.PersistentFlags().BoolVarP(&copyPass, "copy", "c", false, "Copy your password to the clipboard")
+	showCmd.Flags().StringVar(&showField, "field", "", "Only print the value of this field: username, password, url, notes or a custom field")
+	showCmd.Flags().IntVar(&showRevision, "revision", 0, "Print a previous password instead, 1 being the last one replaced, see mypass history")
+	showCmd.PersistentFlags().DurationVar(&clearAfter, "clear-after", clip.DefaultClearAfter, "Clear the copied password from the clipboard after this duration, 0 keeps it")
 }
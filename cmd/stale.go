@@ -0,0 +1,64 @@
+/*
+Copyright © 2022 JEREMY PHUA <jeremyphuachengtoon@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jeremyphua/mypass/stale"
+	"github.com/spf13/cobra"
+)
+
+// ageValue is a duration flag that also accepts days and weeks, e.g. 180d or 4w
+type ageValue time.Duration
+
+func (a *ageValue) String() string {
+	d := time.Duration(*a)
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+func (a *ageValue) Set(s string) error {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, suffix)); err == nil && strings.HasSuffix(s, suffix) {
+			*a = ageValue(time.Duration(n) * unit)
+			return nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid age %q, use e.g. 180d, 4w or 12h", s)
+	}
+	*a = ageValue(d)
+	return nil
+}
+
+func (a *ageValue) Type() string {
+	return "age"
+}
+
+var olderThan = ageValue(180 * 24 * time.Hour)
+
+// staleCmd represents the stale command
+var staleCmd = &cobra.Command{
+	Use:     "stale",
+	Example: "mypass stale --older-than 180d",
+	Short:   "List the sites whose password has not been changed for a while",
+	Long:    `List the sites whose password was last changed longer ago than --older-than, oldest first. Sites added before mypass recorded the time of password changes are listed too. Exits with status 1 when any site is listed, so that it can enforce a rotation policy in scripts.`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return stale.List(time.Duration(olderThan))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(staleCmd)
+	staleCmd.Flags().Var(&olderThan, "older-than", "Age of the passwords to list, in days (180d), weeks (4w) or a duration (12h)")
+}
@@ -2,130 +2,187 @@ package edit
 
 import (
 	"fmt"
-	"log"
-	"os"
-	"path/filepath"
 
+	"github.com/jeremyphua/mypass/find"
+	"github.com/jeremyphua/mypass/generate"
 	"github.com/jeremyphua/mypass/io"
-	"github.com/jeremyphua/mypass/pc"
+	"github.com/jeremyphua/mypass/session"
+	"github.com/jeremyphua/mypass/vault"
 )
 
-func EditInformation(name string) {
-	validInput := false
+// Options of EditInformation, any of them set skips the interactive questions
+type Options struct {
+	Username *string
+	Password *string
+	// Generate a new password instead, it is never shown
+	Generate *generate.Options
+	// URL, notes and custom fields to change
+	Details vault.Details
+	// Prompt offers the closest sites in a chooser when there is no site called name
+	Prompt bool
+}
 
-	for validInput == false {
+// EditInformation changes the username, password and details given in opts,
+// or else asks whether to change the username or password of a site.
+func EditInformation(name string, opts Options) error {
+	if opts.Username != nil || opts.Password != nil || opts.Generate != nil || !opts.Details.IsEmpty() {
+		return editNonInteractive(name, opts)
+	}
+	for {
 		// prompt user whether they want to change username or password
-		usernameOrPassword := io.Prompt(fmt.Sprintf("Do you want to change your username or password for %s?\n", name))
+		usernameOrPassword, err := io.Prompt(fmt.Sprintf("Do you want to change your username or password for %s?\n", name))
+		if err != nil {
+			return err
+		}
 		if usernameOrPassword == "password" {
-			editPassword(name)
-			validInput = true
+			return editPassword(name, opts.Prompt)
 		} else if usernameOrPassword == "username" {
-			editUserName(name)
-			validInput = true
-		} else {
-			fmt.Println("Invalid input. Please choose either username or password.")
+			return editUserName(name, opts.Prompt)
 		}
+		fmt.Println("Invalid input. Please choose either username or password.")
 	}
 }
 
-func editPassword(name string) {
-	// get sites.json
-	sites := io.GetSites()
-	for index, siteInfo := range sites {
-		if siteInfo.Name == name {
-			// validate master password
-			// assign to empty variable because we do not need the master private key
-			_ = pc.GetMasterPrivKey()
-			newPass, err := io.PromptPass(fmt.Sprintf("Enter new password for %s", name))
-			if err != nil {
-				log.Fatalf("Could not read entered password: %s", err)
-			}
-			newSiteInfo, passSealed := pc.ReEncrypt(siteInfo, newPass)
-			sites[index] = newSiteInfo
-			// update sites.json
-			err = io.UpdateSiteFile(sites)
-			if err != nil {
-				log.Fatalf("Could not edit %s in sites.json: %s", name, err.Error())
-			}
-			// update sealed password in vault folder
-			err = io.UpdateVaultFile(name, passSealed)
-			if err != nil {
-				log.Fatalf("Could not edit password in %s: %s", name, err.Error())
-			}
-		}
+// Open the vault, find the site, offering the closest ones if there is no such site and
+// prompt is set, and validate the master password
+func openSite(name string, prompt bool) (*vault.Vault, io.SiteInfo, error) {
+	v, err := session.OpenDefault()
+	if err != nil {
+		return nil, io.SiteInfo{}, err
+	}
+	if err = session.UnlockIndex(v); err != nil {
+		return nil, io.SiteInfo{}, err
+	}
+	if name, err = find.Resolve(v, name, prompt); err != nil {
+		return nil, io.SiteInfo{}, err
+	}
+	siteInfo, err := v.Site(name)
+	if err != nil {
+		return nil, siteInfo, err
+	}
+	if err = session.Unlock(v); err != nil {
+		return nil, siteInfo, err
 	}
+	return v, siteInfo, nil
 }
 
-func editUserName(name string) {
-	// get sites.json
-	sites := io.GetSites()
-	for index, siteInfo := range sites {
-		if siteInfo.Name == name {
-			// validate master password
-			// assign to empty variable because we do not need the master private key
-			_ = pc.GetMasterPrivKey()
-			newUsername := io.Prompt(fmt.Sprintf("Enter new username for %s: ", name))
-			siteInfo.Username = newUsername
-			sites[index] = siteInfo
-			// update sites.json
-			err := io.UpdateSiteFile(sites)
-			if err != nil {
-				log.Fatalf("Could not edit %s in sites.json: %s", name, err.Error())
-			}
-		}
+func editPassword(name string, prompt bool) error {
+	v, siteInfo, err := openSite(name, prompt)
+	if err != nil {
+		return err
 	}
+	newPass, err := io.PromptPass(fmt.Sprintf("Enter new password for %s", siteInfo.Name))
+	if err != nil {
+		return fmt.Errorf("could not read entered password: %w", err)
+	}
+	return v.Put(siteInfo, newPass)
 }
 
-func DeleteSite(site string) {
-	sites := io.GetSites()
-	pathIndex := -1
-	for index, siteInfo := range sites {
-		if siteInfo.Name == site {
-			// validate master password
-			// assign to empty variable because we do not need the master private key
-			_ = pc.GetMasterPrivKey()
-			pathIndex = index
-			vault, err := io.GetVaultFolder()
-			if err != nil {
-				go log.Fatalf("Could not get vault: %s", err.Error())
-			}
-			filePath := filepath.Join(vault, site)
-			err = os.Remove(filePath)
-			if err != nil {
-				log.Fatalf("Attempted to remove file but was unable to: %s", err.Error())
-			}
-			break
+// Apply the username, password and details of opts in a single write
+func editNonInteractive(name string, opts Options) error {
+	v, siteInfo, err := openSite(name, opts.Prompt)
+	if err != nil {
+		return err
+	}
+	if opts.Username != nil {
+		siteInfo.Username = *opts.Username
+	}
+	if err = v.ApplyDetails(&siteInfo, opts.Details); err != nil {
+		return err
+	}
+	var newPass string
+	switch {
+	case opts.Password != nil:
+		newPass = *opts.Password
+	case opts.Generate != nil:
+		if newPass, err = opts.Generate.New(); err != nil {
+			return err
 		}
+	default:
+		if err = v.Update(siteInfo); err != nil {
+			return err
+		}
+		fmt.Printf("Successfully updated %s\n", siteInfo.Name)
+		return nil
+	}
+	if err = v.Put(siteInfo, newPass); err != nil {
+		return err
 	}
-	if pathIndex == -1 {
-		log.Fatalf("Could not find %s in vault", site)
+	fmt.Printf("Successfully changed password of %s\n", siteInfo.Name)
+	if opts.Generate != nil {
+		return opts.Generate.Deliver(newPass)
 	}
-	sites = append(sites[:pathIndex], sites[pathIndex+1:]...)
-	err := io.UpdateSiteFile(sites)
+	return nil
+}
+
+func editUserName(name string, prompt bool) error {
+	v, siteInfo, err := openSite(name, prompt)
 	if err != nil {
-		log.Fatalf("Could not update password vault: %s", err.Error())
+		return err
 	}
-	fmt.Printf("Successfully deleted credentials for %s", site)
+	newUsername, err := io.Prompt(fmt.Sprintf("Enter new username for %s: ", siteInfo.Name))
+	if err != nil {
+		return err
+	}
+	siteInfo.Username = newUsername
+	return v.Update(siteInfo)
 }
 
-func Rename(site string) {
-	sites := io.GetSites()
-	for index, siteInfo := range sites {
-		if siteInfo.Name == site {
-			// validate master password
-			// assign to empty variable because we do not need the master private key
-			_ = pc.GetMasterPrivKey()
-			newSiteName := io.Prompt(fmt.Sprintf("Enter new sitename for %s: ", site))
-			sites[index] = io.SiteInfo{
-				PubKey:   siteInfo.PubKey,
-				Name:     newSiteName,
-				Username: siteInfo.Username,
-			}
-			err := io.UpdateSiteFile(sites)
-			if err != nil {
-				log.Fatalf("Could not edit %s in sites.json: %s", site, err.Error())
-			}
-			io.UpdateFileName(site, newSiteName)
+// DeleteSite removes a site after asking for confirmation, unless yes is set.
+// The closest sites are offered when there is no such site only if prompt is set.
+func DeleteSite(site string, yes, prompt bool) error {
+	v, siteInfo, err := openSite(site, prompt)
+	if err != nil {
+		return err
+	}
+	site = siteInfo.Name
+	if !yes {
+		ok, err := io.Confirm(fmt.Sprintf("Delete the credentials for %s?", site))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Nothing was deleted")
+			return nil
 		}
 	}
+	if err = v.Delete(site); err != nil {
+		return err
+	}
+	fmt.Printf("Successfully deleted credentials for %s\n", site)
+	return nil
+}
+
+// Rename moves a site to newSiteName, which is prompted for when empty, after asking for
+// confirmation unless yes is set. The closest sites are offered when there is no such site
+// only if prompt is set.
+func Rename(site, newSiteName string, yes, prompt bool) error {
+	v, siteInfo, err := openSite(site, prompt)
+	if err != nil {
+		return err
+	}
+	site = siteInfo.Name
+	if newSiteName == "" {
+		if newSiteName, err = io.Prompt(fmt.Sprintf("Enter new sitename for %s: ", site)); err != nil {
+			return err
+		}
+	}
+	if newSiteName, err = io.NormalizeSiteName(newSiteName); err != nil {
+		return err
+	}
+	if !yes {
+		ok, err := io.Confirm(fmt.Sprintf("Rename %s to %s?", site, newSiteName))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Nothing was renamed")
+			return nil
+		}
+	}
+	if err = v.Rename(site, newSiteName); err != nil {
+		return err
+	}
+	fmt.Printf("Successfully renamed %s to %s\n", site, newSiteName)
+	return nil
 }
@@ -0,0 +1,51 @@
+package encrypt
+
+import (
+	"fmt"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/session"
+)
+
+// EncryptIndex seals sites.json and replaces the file names in the vault folder with opaque ones,
+// so that the sites and usernames can't be read without the master password.
+// The backups in the vault directory still show the sites, they are only removed when removeBackups is set.
+// With encrypt false an encrypted index is turned back into plaintext.
+func EncryptIndex(encrypt, removeBackups bool) error {
+
+	if err := io.RequireVault(); err != nil {
+		return err
+	}
+
+	v, err := session.OpenDefault()
+	if err != nil {
+		return err
+	}
+	if v.IndexEncrypted() == encrypt {
+		fmt.Println(status(encrypt))
+		return nil
+	}
+	if err = session.Unlock(v); err != nil {
+		return err
+	}
+
+	removed, err := v.EncryptIndex(encrypt, removeBackups)
+	for _, path := range removed {
+		fmt.Printf("Removed backup revealing the site names: %s\n", path)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(status(encrypt))
+	if encrypt && !removeBackups {
+		fmt.Println("The backups in the vault directory still reveal the site names, remove them with --remove-backups")
+	}
+	return nil
+}
+
+func status(encrypted bool) string {
+	if encrypted {
+		return "The index of the vault is encrypted"
+	}
+	return "The index of the vault is stored in plaintext"
+}
@@ -0,0 +1,158 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	goio "io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/pc"
+)
+
+// ArchiveFormat identifies an encrypted export
+const ArchiveFormat = "mypass-export"
+
+const archiveVersion = 1
+
+// ErrWrongPassphrase is returned when the passphrase does not open an archive
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted archive")
+
+// ErrEmptyPassphrase is returned when the passphrase of an archive is empty
+var ErrEmptyPassphrase = errors.New("the passphrase of the archive cannot be empty")
+
+// archiveFile is the on-disk format of an encrypted export, the sites
+// sealed with a key derived from the passphrase like the master password key
+type archiveFile struct {
+	Format  string
+	Version int
+	Salt    []byte
+	// Argon2 are the parameters deriving the key from the passphrase, pc.LegacyArgon2Params when nil
+	Argon2 *io.Argon2Params `json:",omitempty"`
+	Sealed []byte
+}
+
+// archive is the plaintext sealed in an archiveFile
+type archive struct {
+	Exported time.Time
+	Sites    []Site
+}
+
+var passphraseFile string
+
+// SetPassphraseFile makes exports and imports of archives read the passphrase from a file
+// instead of the terminal. Only its first line is used.
+func SetPassphraseFile(path string) {
+	passphraseFile = path
+}
+
+// Read the passphrase of an archive, a new one is asked twice. Empty passphrases are refused
+// whether they come from the passphrase file or the terminal.
+func passphrase(isNew bool) (string, error) {
+	pass, err := readPassphrase(isNew)
+	if err != nil {
+		return "", err
+	}
+	if pass == "" {
+		return "", ErrEmptyPassphrase
+	}
+	return pass, nil
+}
+
+func readPassphrase(isNew bool) (string, error) {
+	if passphraseFile != "" {
+		f, err := os.Open(passphraseFile)
+		if err != nil {
+			return "", fmt.Errorf("could not open passphrase file: %w", err)
+		}
+		defer f.Close()
+		line, err := bufio.NewReader(f).ReadString('\n')
+		if err != nil && !errors.Is(err, goio.EOF) {
+			return "", fmt.Errorf("could not read passphrase file: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	if !isNew {
+		return io.PromptPass("Please enter the passphrase of the archive")
+	}
+	pass, err := io.PromptPass("Please enter a passphrase for the archive")
+	if err != nil || pass == "" {
+		return pass, err
+	}
+	confirm, err := io.PromptPass("Please confirm the passphrase")
+	if err != nil {
+		return "", err
+	}
+	if pass != confirm {
+		return "", errors.New("passphrases do not match")
+	}
+	return pass, nil
+}
+
+func writeArchive(f *os.File, sites []Site) error {
+	pass, err := passphrase(true)
+	if err != nil {
+		return fmt.Errorf("could not read passphrase: %w", err)
+	}
+	plaintext, err := json.Marshal(archive{Exported: time.Now().UTC(), Sites: sites})
+	if err != nil {
+		return fmt.Errorf("could not marshal sites: %w", err)
+	}
+	salt, err := pc.NewSalt()
+	if err != nil {
+		return err
+	}
+	params := pc.DefaultArgon2Params()
+	key, err := pc.DeriveKey(pass, salt, &params)
+	if err != nil {
+		return err
+	}
+	sealed, err := pc.SecretboxSeal(key, plaintext)
+	if err != nil {
+		return fmt.Errorf("could not seal archive: %w", err)
+	}
+	data, err := json.MarshalIndent(archiveFile{Format: ArchiveFormat, Version: archiveVersion, Salt: salt, Argon2: &params, Sealed: sealed}, "", "\t")
+	if err != nil {
+		return fmt.Errorf("could not marshal archive: %w", err)
+	}
+	if _, err = f.Write(data); err != nil {
+		return fmt.Errorf("could not write export file: %w", err)
+	}
+	return nil
+}
+
+// ReadArchive opens an archive written by an encrypted export, asking for its passphrase
+func ReadArchive(file string) ([]Site, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read archive: %w", err)
+	}
+	var af archiveFile
+	if err = json.Unmarshal(data, &af); err != nil || af.Format != ArchiveFormat {
+		return nil, fmt.Errorf("%s is not a mypass export archive", file)
+	}
+	if af.Version > archiveVersion {
+		return nil, fmt.Errorf("archive version %d was written by a newer version of mypass", af.Version)
+	}
+	pass, err := passphrase(false)
+	if err != nil {
+		return nil, fmt.Errorf("could not read passphrase: %w", err)
+	}
+	key, err := pc.DeriveKey(pass, af.Salt, af.Argon2)
+	if err != nil {
+		return nil, fmt.Errorf("could not open archive: %w", err)
+	}
+	plaintext, ok := pc.SecretboxOpen(key, af.Sealed)
+	if !ok {
+		return nil, ErrWrongPassphrase
+	}
+	var a archive
+	if err = json.Unmarshal(plaintext, &a); err != nil {
+		return nil, fmt.Errorf("could not unmarshal archive: %w", err)
+	}
+	return a.Sites, nil
+}
@@ -0,0 +1,174 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/session"
+	"github.com/jeremyphua/mypass/vault"
+)
+
+// Formats of a plaintext export
+const (
+	CSV  = "csv"
+	JSON = "json"
+)
+
+// PlaintextFormats are the values of export --format
+var PlaintextFormats = []string{CSV, JSON}
+
+// ErrNotConfirmed is returned when a plaintext export was not explicitly confirmed
+var ErrNotConfirmed = errors.New("a plaintext export writes every password unencrypted, confirm it with --i-understand")
+
+// Site is the decrypted content of a site as exported
+type Site struct {
+	Name            string
+	Username        string
+	Password        string
+	URL             string     `json:",omitempty"`
+	Notes           string     `json:",omitempty"`
+	Fields          []Field    `json:",omitempty"`
+	History         []Revision `json:",omitempty"`
+	Created         time.Time
+	PasswordChanged time.Time
+	LastShown       time.Time
+}
+
+// Field is a decrypted custom field
+type Field struct {
+	Name   string
+	Value  string
+	Secret bool `json:",omitempty"`
+}
+
+// Revision is a decrypted previous password
+type Revision struct {
+	Password string
+	Replaced time.Time
+}
+
+// Options of Export
+type Options struct {
+	// Plaintext writes Format instead of an encrypted archive
+	Plaintext bool
+	// Format of a plaintext export, CSV or JSON
+	Format string
+	// Confirmed must be set for a plaintext export
+	Confirmed bool
+}
+
+// Export writes every site of the vault to file, decrypted, either sealed in an archive
+// with a passphrase or as a plaintext CSV or JSON file. The file must not exist yet.
+func Export(file string, opts Options) error {
+	// a plaintext export is confirmed before asking for the master password
+	if _, err := opts.writer(); err != nil {
+		return err
+	}
+	if err := io.RequireVault(); err != nil {
+		return err
+	}
+	v, err := session.OpenDefault()
+	if err != nil {
+		return err
+	}
+	if err = session.Unlock(v); err != nil {
+		return err
+	}
+	return exportVault(v, file, opts)
+}
+
+// Write the sites of the unlocked vault v to file
+func exportVault(v *vault.Vault, file string, opts Options) error {
+	write, err := opts.writer()
+	if err != nil {
+		return err
+	}
+	sites, err := decryptAll(v)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("could not create export file: %w", err)
+	}
+	if err = write(f, sites); err != nil {
+		f.Close()
+		os.Remove(file)
+		return err
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(file)
+		return fmt.Errorf("could not write export file: %w", err)
+	}
+	if opts.Plaintext {
+		fmt.Printf("Exported %d sites unencrypted to %s, delete it once you are done with it\n", len(sites), file)
+	} else {
+		fmt.Printf("Exported %d sites to %s\n", len(sites), file)
+	}
+	return nil
+}
+
+// The function writing the export chosen by o
+func (o Options) writer() (func(f *os.File, sites []Site) error, error) {
+	switch {
+	case !o.Plaintext:
+		return writeArchive, nil
+	case !o.Confirmed:
+		return nil, ErrNotConfirmed
+	case o.Format == CSV:
+		return writeCSV, nil
+	case o.Format == JSON:
+		return writeJSON, nil
+	default:
+		return nil, fmt.Errorf("unknown format %s, use csv or json", o.Format)
+	}
+}
+
+// Decrypt the password, notes, secret fields and history of every site, sorted by name
+func decryptAll(v *vault.Vault) ([]Site, error) {
+	list, err := v.List()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	sites := make([]Site, 0, len(list))
+	for _, si := range list {
+		_, password, err := v.Get(si.Name)
+		if err != nil {
+			return nil, err
+		}
+		s := Site{
+			Name:            si.Name,
+			Username:        si.Username,
+			Password:        password,
+			URL:             si.URL,
+			Created:         si.Created,
+			PasswordChanged: si.PasswordChanged,
+			LastShown:       si.LastShown,
+		}
+		if s.Notes, err = v.Notes(si); err != nil {
+			return nil, err
+		}
+		for _, f := range si.Fields {
+			value, err := v.FieldValue(f)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", si.Name, err)
+			}
+			s.Fields = append(s.Fields, Field{Name: f.Name, Value: value, Secret: f.IsSecret()})
+		}
+		for i := range si.History {
+			rev, password, err := v.Revision(si.Name, i+1)
+			if err != nil {
+				return nil, err
+			}
+			s.History = append(s.History, Revision{Password: password, Replaced: rev.Replaced})
+		}
+		sites = append(sites, s)
+	}
+	return sites, nil
+}
@@ -0,0 +1,164 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/pc"
+	"github.com/jeremyphua/mypass/vault"
+)
+
+const testPassword = "master password"
+
+// Write s to a file of a temporary folder and return its path
+func writeTemp(t *testing.T, name, s string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(s), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Make a vault in memory with two sites, unlocked
+func newTestVault(t *testing.T) *vault.Vault {
+	t.Helper()
+	v, err := vault.Create(vault.NewMemStorage(), testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bank := io.SiteInfo{Name: "money/bank", Username: "jeremy"}
+	notes := "joint account"
+	err = v.ApplyDetails(&bank, vault.Details{Notes: &notes, Fields: []vault.FieldInput{
+		{Name: "branch", Value: "Orchard"},
+		{Name: "totp", Value: "JBSWY3DP", Secret: true},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, password := range []string{"old", "new"} {
+		if err = v.Put(bank, password); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = v.Add(io.SiteInfo{Name: "mail", Username: "me", URL: "https://mail.com"}, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	v := newTestVault(t)
+	want, err := decryptAll(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != 2 || want[1].Name != "money/bank" || len(want[1].History) != 1 || want[1].History[0].Password != "old" {
+		t.Fatalf("decryptAll(v) = %+v", want)
+	}
+
+	SetPassphraseFile(writeTemp(t, "passphrase", "secret\n"))
+	t.Cleanup(func() { SetPassphraseFile("") })
+	file := filepath.Join(t.TempDir(), "mypass.archive")
+	if err = exportVault(v, file, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("archive has mode %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+	// the Argon2id parameters are stored for the import to derive the same key
+	var af archiveFile
+	data, _ := os.ReadFile(file)
+	if err = json.Unmarshal(data, &af); err != nil || af.Argon2 == nil || *af.Argon2 != pc.DefaultArgon2Params() {
+		t.Fatalf("archive has Argon2 params %v, %v", af.Argon2, err)
+	}
+
+	got, err := ReadArchive(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ReadArchive() = %+v, want %+v", got, want)
+	}
+
+	SetPassphraseFile(writeTemp(t, "passphrase", "wrong\n"))
+	if _, err = ReadArchive(file); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("ReadArchive() with the wrong passphrase = %v, want ErrWrongPassphrase", err)
+	}
+	// an existing file is never overwritten
+	if err = exportVault(v, file, Options{}); err == nil {
+		t.Fatal("exportVault() over an existing file succeeded")
+	}
+}
+
+func TestEmptyPassphrase(t *testing.T) {
+	v := newTestVault(t)
+	for _, content := range []string{"", "\n"} {
+		SetPassphraseFile(writeTemp(t, "passphrase", content))
+		t.Cleanup(func() { SetPassphraseFile("") })
+		file := filepath.Join(t.TempDir(), "mypass.archive")
+		if err := exportVault(v, file, Options{}); !errors.Is(err, ErrEmptyPassphrase) {
+			t.Fatalf("exportVault() with the passphrase %q = %v, want ErrEmptyPassphrase", content, err)
+		}
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Fatalf("a failed export left %s: %v", file, err)
+		}
+	}
+}
+
+func TestPlaintextExport(t *testing.T) {
+	v := newTestVault(t)
+	dir := t.TempDir()
+	if err := exportVault(v, filepath.Join(dir, "export.csv"), Options{Plaintext: true, Format: CSV}); !errors.Is(err, ErrNotConfirmed) {
+		t.Fatalf("exportVault() without confirmation = %v, want ErrNotConfirmed", err)
+	}
+
+	file := filepath.Join(dir, "export.csv")
+	if err := exportVault(v, file, Options{Plaintext: true, Format: CSV, Confirmed: true}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		csvHeader,
+		{"", "", "login", "mail", "", "", "0", "https://mail.com", "me", "pw", ""},
+		{"money", "", "login", "bank", "joint account", "branch: Orchard", "0", "", "jeremy", "new", "JBSWY3DP"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("CSV export is %q, want %q", records, want)
+	}
+
+	file = filepath.Join(dir, "export.json")
+	if err = exportVault(v, file, Options{Plaintext: true, Format: JSON, Confirmed: true}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var export bitwardenExport
+	if err = json.Unmarshal(data, &export); err != nil {
+		t.Fatal(err)
+	}
+	if len(export.Folders) != 1 || export.Folders[0].Name != "money" || len(export.Items) != 2 {
+		t.Fatalf("JSON export has folders %+v and %d items", export.Folders, len(export.Items))
+	}
+	bank := export.Items[1]
+	if bank.Login.Password != "new" || bank.Login.TOTP == nil || *bank.Login.TOTP != "JBSWY3DP" || *bank.FolderID != export.Folders[0].ID {
+		t.Fatalf("JSON export of money/bank is %+v", bank)
+	}
+}
@@ -0,0 +1,135 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// The Bitwarden CSV and JSON formats are read by most password managers,
+// groups become folders and a secret field named totp the TOTP secret
+const totpField = "totp"
+
+var csvHeader = []string{"folder", "favorite", "type", "name", "notes", "fields", "reprompt", "login_uri", "login_username", "login_password", "login_totp"}
+
+func writeCSV(f *os.File, sites []Site) error {
+	w := csv.NewWriter(f)
+	if err := w.Write(csvHeader); err != nil {
+		return fmt.Errorf("could not write export file: %w", err)
+	}
+	for _, s := range sites {
+		folder, name := splitName(s.Name)
+		totp, fields := "", []string{}
+		for _, field := range s.Fields {
+			if field.Name == totpField && field.Secret {
+				totp = field.Value
+				continue
+			}
+			fields = append(fields, field.Name+": "+field.Value)
+		}
+		record := []string{folder, "", "login", name, s.Notes, strings.Join(fields, "\n"), "0", s.URL, s.Username, s.Password, totp}
+		if err := w.Write(record); err != nil {
+			return fmt.Errorf("could not write export file: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("could not write export file: %w", err)
+	}
+	return nil
+}
+
+type bitwardenFolder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type bitwardenField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  int    `json:"type"`
+}
+
+type bitwardenURI struct {
+	Match *int   `json:"match"`
+	URI   string `json:"uri"`
+}
+
+type bitwardenItem struct {
+	ID       string           `json:"id"`
+	FolderID *string          `json:"folderId"`
+	Type     int              `json:"type"`
+	Name     string           `json:"name"`
+	Notes    *string          `json:"notes"`
+	Favorite bool             `json:"favorite"`
+	Fields   []bitwardenField `json:"fields,omitempty"`
+	Login    struct {
+		Username string         `json:"username"`
+		Password string         `json:"password"`
+		TOTP     *string        `json:"totp"`
+		URIs     []bitwardenURI `json:"uris,omitempty"`
+	} `json:"login"`
+}
+
+type bitwardenExport struct {
+	Encrypted bool              `json:"encrypted"`
+	Folders   []bitwardenFolder `json:"folders"`
+	Items     []bitwardenItem   `json:"items"`
+}
+
+func writeJSON(f *os.File, sites []Site) error {
+	export := bitwardenExport{Folders: []bitwardenFolder{}, Items: []bitwardenItem{}}
+	folderIDs := map[string]string{}
+	for i, s := range sites {
+		folder, name := splitName(s.Name)
+		item := bitwardenItem{ID: fmt.Sprintf("site-%d", i+1), Type: 1, Name: name}
+		if folder != "" {
+			if _, ok := folderIDs[folder]; !ok {
+				folderIDs[folder] = fmt.Sprintf("folder-%d", len(folderIDs)+1)
+				export.Folders = append(export.Folders, bitwardenFolder{ID: folderIDs[folder], Name: folder})
+			}
+			id := folderIDs[folder]
+			item.FolderID = &id
+		}
+		if s.Notes != "" {
+			notes := s.Notes
+			item.Notes = &notes
+		}
+		item.Login.Username = s.Username
+		item.Login.Password = s.Password
+		if s.URL != "" {
+			item.Login.URIs = []bitwardenURI{{URI: s.URL}}
+		}
+		for _, field := range s.Fields {
+			if field.Name == totpField && field.Secret {
+				totp := field.Value
+				item.Login.TOTP = &totp
+				continue
+			}
+			// Bitwarden text and hidden fields
+			fieldType := 0
+			if field.Secret {
+				fieldType = 1
+			}
+			item.Fields = append(item.Fields, bitwardenField{Name: field.Name, Value: field.Value, Type: fieldType})
+		}
+		export.Items = append(export.Items, item)
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal sites: %w", err)
+	}
+	if _, err = f.Write(data); err != nil {
+		return fmt.Errorf("could not write export file: %w", err)
+	}
+	return nil
+}
+
+// Split a site name into its group and the name within the group
+func splitName(name string) (group, site string) {
+	group, site = path.Split(name)
+	return strings.TrimSuffix(group, "/"), site
+}
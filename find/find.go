@@ -0,0 +1,101 @@
+package find
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/session"
+	"github.com/jeremyphua/mypass/vault"
+)
+
+// maxSuggestions is how many sites a "did you mean" error or the chooser offers
+const maxSuggestions = 5
+
+// Sites prints the sites matching query with their username and what matched, best first.
+// It fails with io.ErrSiteNotFound when nothing matches.
+func Sites(query string, limit int) error {
+	if err := io.RequireVault(); err != nil {
+		return err
+	}
+
+	v, err := session.OpenDefault()
+	if err != nil {
+		return err
+	}
+	if err = session.UnlockIndex(v); err != nil {
+		return err
+	}
+	sites, err := v.List()
+	if err != nil {
+		return err
+	}
+
+	matches := Matches(sites, query)
+	if len(matches) == 0 {
+		return fmt.Errorf("%w: nothing matches %s", io.ErrSiteNotFound, query)
+	}
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	for _, m := range matches {
+		fmt.Printf("%-30s %-25s %s match on %s\n", m.Site.Name, m.Site.Username, m.Kind, m.Field)
+	}
+	return nil
+}
+
+// Resolve returns the name of the site called name. When there is none, the closest sites
+// are offered in a chooser if prompt is set, or else suggested in the returned error.
+func Resolve(v *vault.Vault, name string, prompt bool) (string, error) {
+	si, err := v.Site(name)
+	if err == nil {
+		return si.Name, nil
+	}
+	// a query such as "ocbc bank" is not a valid site name but can still match one
+	if !errors.Is(err, io.ErrSiteNotFound) && !errors.Is(err, io.ErrInvalidSiteName) {
+		return "", err
+	}
+	sites, lerr := v.List()
+	if lerr != nil {
+		return "", lerr
+	}
+	matches := Matches(sites, name)
+	if len(matches) == 0 {
+		return "", err
+	}
+	if len(matches) > maxSuggestions {
+		matches = matches[:maxSuggestions]
+	}
+	if !prompt {
+		names := make([]string, len(matches))
+		for i, m := range matches {
+			names[i] = m.Site.Name
+		}
+		return "", fmt.Errorf("%w, did you mean %s?", err, strings.Join(names, ", "))
+	}
+	return choose(name, matches)
+}
+
+// Ask which of the matches was meant, an empty answer cancels
+func choose(name string, matches []Match) (string, error) {
+	fmt.Printf("No site is called %s, did you mean:\n", name)
+	for i, m := range matches {
+		fmt.Printf("  %d) %s\n", i+1, m.Site.Name)
+	}
+	for {
+		answer, err := io.Prompt(fmt.Sprintf("Choose a site [1-%d], or press enter to cancel: ", len(matches)))
+		if err != nil {
+			return "", err
+		}
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			return "", fmt.Errorf("%w: %s", io.ErrSiteNotFound, name)
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(matches) {
+			return matches[n-1].Site.Name, nil
+		}
+		fmt.Println("Invalid choice.")
+	}
+}
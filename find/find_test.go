@@ -0,0 +1,33 @@
+package find
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/vault"
+)
+
+func TestResolveWithoutPrompt(t *testing.T) {
+	v, err := vault.Create(vault.NewMemStorage(), "master password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, si := range testSites[:4] {
+		if err = v.Add(si, "pw"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := Resolve(v, "money/ocbc", false); err != nil || got != "money/ocbc" {
+		t.Fatalf("Resolve(money/ocbc) = %q, %v", got, err)
+	}
+	// the chooser is never opened, the closest sites are suggested instead
+	_, err = Resolve(v, "githbu", false)
+	if !errors.Is(err, io.ErrSiteNotFound) || !strings.Contains(err.Error(), "did you mean personal/github, work/github?") {
+		t.Fatalf("Resolve(githbu) = %v, want ErrSiteNotFound suggesting both github sites", err)
+	}
+	if _, err = Resolve(v, "zzzzzz", false); !errors.Is(err, io.ErrSiteNotFound) || strings.Contains(err.Error(), "did you mean") {
+		t.Fatalf("Resolve(zzzzzz) = %v, want ErrSiteNotFound without suggestions", err)
+	}
+}
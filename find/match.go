@@ -0,0 +1,210 @@
+package find
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jeremyphua/mypass/io"
+)
+
+// How a site matched a query, from the best to the weakest match
+const (
+	Exact     = "exact"
+	Substring = "substring"
+	Glob      = "glob"
+	Fuzzy     = "fuzzy"
+)
+
+// Match is a site matching a query
+type Match struct {
+	Site io.SiteInfo
+	// Kind is Exact, Substring, Glob or Fuzzy
+	Kind string
+	// Field is what matched: name, username, url or the name of a custom field
+	Field string
+	// Score ranks the matches, higher is better
+	Score int
+}
+
+// Base scores of the kinds of matches, the site name scores above the other fields
+const (
+	scoreExact     = 1000
+	scoreSubstring = 700
+	scoreGlob      = 600
+	scoreFuzzy     = 300
+	scoreTypo      = 200
+	// lost by matches on the username, URL and fields rather than the name
+	otherFieldPenalty = 150
+)
+
+// Matches ranks the sites matching query, best first. A query with * ? or [ is a glob
+// matched against whole site names and the name within their group. Other queries match
+// site names and usernames as substrings or fuzzily, as letters in order or with a few typos,
+// and the URL and plain custom fields as substrings. Matching ignores case.
+func Matches(sites io.SiteFile, query string) []Match {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+	glob := strings.ContainsAny(query, "*?[")
+	var matches []Match
+	for _, si := range sites {
+		var m Match
+		var ok bool
+		if glob {
+			m, ok = matchGlob(si, query)
+		} else {
+			m, ok = matchText(si, query)
+		}
+		if ok {
+			matches = append(matches, m)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Site.Name < matches[j].Site.Name
+	})
+	return matches
+}
+
+func matchGlob(si io.SiteInfo, pattern string) (Match, bool) {
+	name := strings.ToLower(si.Name)
+	if ok, _ := path.Match(pattern, name); ok {
+		return Match{Site: si, Kind: Glob, Field: "name", Score: scoreGlob}, true
+	}
+	if ok, _ := path.Match(pattern, path.Base(name)); ok {
+		return Match{Site: si, Kind: Glob, Field: "name", Score: scoreGlob - 1}, true
+	}
+	if ok, _ := path.Match(pattern, strings.ToLower(si.Username)); ok && si.Username != "" {
+		return Match{Site: si, Kind: Glob, Field: "username", Score: scoreGlob - otherFieldPenalty}, true
+	}
+	return Match{}, false
+}
+
+func matchText(si io.SiteInfo, query string) (Match, bool) {
+	best := Match{Site: si}
+	try := func(field, value string, penalty int) {
+		if value == "" {
+			return
+		}
+		if kind, score := scoreText(strings.ToLower(value), query); score-penalty > best.Score {
+			best.Kind, best.Field, best.Score = kind, field, score-penalty
+		}
+	}
+	try("name", si.Name, 0)
+	try("username", si.Username, otherFieldPenalty)
+	// URLs and fields only match as substrings, they are often long enough to match anything fuzzily
+	others := map[string]string{"url": si.URL}
+	for _, f := range si.Fields {
+		if !f.IsSecret() {
+			others[f.Name] = f.Value
+		}
+	}
+	for field, value := range others {
+		if strings.Contains(strings.ToLower(value), query) {
+			if score := scoreSubstring - otherFieldPenalty - 1; score > best.Score {
+				best.Kind, best.Field, best.Score = Substring, field, score
+			}
+		}
+	}
+	return best, best.Score > 0
+}
+
+// Score a lowercase value against a lowercase query, 0 when it does not match
+func scoreText(value, query string) (string, int) {
+	base := path.Base(value)
+	switch {
+	case value == query:
+		return Exact, scoreExact
+	case base == query:
+		return Exact, scoreExact - 1
+	}
+	if i := strings.Index(value, query); i >= 0 {
+		score := scoreSubstring
+		// prefer matches at the start of a segment and in shorter values
+		if i == 0 || value[i-1] == '/' {
+			score += 50
+		}
+		return Substring, score - min(len(value)-len(query), 49)
+	}
+	if score := subsequence(value, query); score > 0 {
+		return Fuzzy, scoreFuzzy + score
+	}
+	// a few typos, compared with the whole name and the name within its group
+	allowed := len(query) / 4
+	if allowed < 1 {
+		allowed = 1
+	}
+	d := distance(value, query)
+	if bd := distance(base, query); bd < d {
+		d = bd
+	}
+	if d <= allowed {
+		return Fuzzy, scoreTypo - 10*d
+	}
+	return "", 0
+}
+
+// Score the letters of query found in order in value, 0 if they are not.
+// Consecutive letters and letters starting a segment score more, gaps score less.
+func subsequence(value, query string) int {
+	score, q, last := 0, 0, -1
+	for i := 0; i < len(value) && q < len(query); i++ {
+		if value[i] != query[q] {
+			continue
+		}
+		score += 2
+		if last == i-1 {
+			score += 3
+		}
+		if i == 0 || strings.ContainsRune("/-_.@ ", rune(value[i-1])) {
+			score += 4
+		}
+		last = i
+		q++
+	}
+	if q < len(query) {
+		return 0
+	}
+	score -= (len(value) - len(query)) / 4
+	if score < 1 {
+		score = 1
+	}
+	// never above the worst substring match
+	return min(score, scoreSubstring-scoreFuzzy-50)
+}
+
+// Edit distance between a and b, counting a swap of two adjacent letters as one edit
+func distance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(min(d[i-1][j]+1, d[i][j-1]+1), d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
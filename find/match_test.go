@@ -0,0 +1,110 @@
+package find
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jeremyphua/mypass/io"
+)
+
+var testSites = io.SiteFile{
+	{Name: "money/ocbc", Username: "jeremy"},
+	{Name: "money/dbs", Username: "jeremy@example.com", URL: "https://www.dbs.com.sg"},
+	{Name: "work/github", Username: "jphua"},
+	{Name: "personal/github", Username: "jeremyphua"},
+	{Name: "gitlab", Username: "jp"},
+	{Name: "mail", Username: "me", Fields: []io.Field{
+		{Name: "recovery", Value: "backup-codes"},
+		{Name: "pin", Sealed: []byte("sealed")},
+	}},
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		query string
+		// names of the matching sites, best first
+		want []string
+		// kind and field of the best match
+		kind, field string
+	}{
+		{query: "money/ocbc", want: []string{"money/ocbc"}, kind: Exact, field: "name"},
+		{query: "OCBC", want: []string{"money/ocbc"}, kind: Exact, field: "name"},
+		{query: "  mail ", want: []string{"mail"}, kind: Exact, field: "name"},
+		{query: "github", want: []string{"personal/github", "work/github"}, kind: Exact, field: "name"},
+		{query: "money", want: []string{"money/dbs", "money/ocbc"}, kind: Substring, field: "name"},
+		{query: "git", want: []string{"gitlab", "work/github", "personal/github"}, kind: Substring, field: "name"},
+		{query: "jphua", want: []string{"work/github", "personal/github"}, kind: Exact, field: "username"},
+		{query: "dbs.com", want: []string{"money/dbs"}, kind: Substring, field: "url"},
+		{query: "backup", want: []string{"mail"}, kind: Substring, field: "recovery"},
+		{query: "sealed", want: nil},
+		{query: "mnyocbc", want: []string{"money/ocbc"}, kind: Fuzzy, field: "name"},
+		{query: "githbu", want: []string{"personal/github", "work/github"}, kind: Fuzzy, field: "name"},
+		{query: "money/*", want: []string{"money/dbs", "money/ocbc"}, kind: Glob, field: "name"},
+		{query: "git*", want: []string{"gitlab", "personal/github", "work/github"}, kind: Glob, field: "name"},
+		{query: "*/git?ub", want: []string{"personal/github", "work/github"}, kind: Glob, field: "name"},
+		{query: "jeremy*", want: []string{"money/dbs", "money/ocbc", "personal/github"}, kind: Glob, field: "username"},
+		{query: "[", want: nil},
+		{query: "", want: nil},
+		{query: "zzzzzz", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			matches := Matches(testSites, tt.query)
+			var got []string
+			for _, m := range matches {
+				got = append(got, m.Site.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Matches(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			if len(matches) == 0 {
+				return
+			}
+			if matches[0].Kind != tt.kind || matches[0].Field != tt.field {
+				t.Fatalf("best match of %q is %s on %s, want %s on %s", tt.query, matches[0].Kind, matches[0].Field, tt.kind, tt.field)
+			}
+		})
+	}
+}
+
+func TestMatchesRanking(t *testing.T) {
+	sites := io.SiteFile{
+		{Name: "shop/amazon-web-services"},
+		{Name: "amazon"},
+		{Name: "shop/amazon"},
+		{Name: "books", Username: "amazon"},
+		{Name: "a/m/a/z/o/n"},
+	}
+	var got []string
+	for _, m := range Matches(sites, "amazon") {
+		got = append(got, m.Site.Name)
+	}
+	// exact name, exact name within its group, exact username, substring, fuzzy
+	want := []string{"amazon", "shop/amazon", "books", "shop/amazon-web-services", "a/m/a/z/o/n"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Matches() = %v, want %v", got, want)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"github", "github", 0},
+		{"github", "githbu", 1},
+		{"github", "gitub", 1},
+		{"github", "gitthub", 1},
+		{"github", "gitlab", 2},
+		{"ocbc", "", 4},
+	}
+	for _, tt := range tests {
+		if got := distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := distance(tt.b, tt.a); got != tt.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package fsck
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jeremyphua/mypass/session"
+	"github.com/jeremyphua/mypass/vault"
+)
+
+// ErrProblemsFound is returned when the check reported at least one problem
+var ErrProblemsFound = errors.New("vault has problems")
+
+// Check reports the inconsistencies between sites.json and the files in the vault folder:
+// invalid names, duplicate sites, sites without a password file, password files without a site
+// and empty folders. With decrypt, or when the index is encrypted, the vault is unlocked
+// and every password is decrypted as well. repair fixes what can be fixed after a rolling backup.
+func Check(decrypt, repair bool) error {
+	v, err := session.OpenDefault()
+	if err != nil {
+		return err
+	}
+
+	if decrypt {
+		err = session.Unlock(v)
+	} else {
+		err = session.UnlockIndex(v)
+	}
+	if err != nil {
+		return err
+	}
+
+	problems, err := v.Check()
+	if err != nil {
+		return err
+	}
+	repairable, lockedDuplicates := 0, 0
+	for _, p := range problems {
+		fmt.Println(p.String())
+		if p.Repairable {
+			repairable++
+		} else if p.Kind == vault.ProblemDuplicate {
+			lockedDuplicates++
+		}
+	}
+	if len(problems) == 0 {
+		fmt.Println("No problems found")
+		return nil
+	}
+	if lockedDuplicates > 0 {
+		fmt.Printf("%d duplicate sites can only be repaired with mypass fsck --repair --decrypt\n", lockedDuplicates)
+	}
+	if !repair {
+		if repairable > 0 {
+			fmt.Printf("%d of the problems can be fixed with mypass fsck --repair\n", repairable)
+		}
+		return fmt.Errorf("%w: found %d problems", ErrProblemsFound, len(problems))
+	}
+
+	fixed, err := v.Repair(problems)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Repaired the vault, removed %d sites, files and folders\n", fixed)
+	if left := len(problems) - repairable; left > 0 {
+		return fmt.Errorf("%w: %d problems cannot be repaired, restore the vault from a backup", ErrProblemsFound, left)
+	}
+	return nil
+}
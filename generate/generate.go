@@ -1,15 +1,45 @@
 package generate
 
 import (
-	"log"
+	"fmt"
+	"time"
 
+	"github.com/jeremyphua/mypass/clip"
 	"github.com/jeremyphua/mypass/pc"
 )
 
-func Password() string {
-	pass, err := pc.GeneratePassword()
+// Password returns a random password following the policy
+func Password(policy pc.Policy) (string, error) {
+	pass, err := pc.GeneratePasswordWithPolicy(policy)
 	if err != nil {
-		log.Fatalf("Could not generate password: %s", err.Error())
+		return "", fmt.Errorf("could not generate password: %w", err)
 	}
-	return pass
+	return pass, nil
+}
+
+// Passphrase returns random words from the EFF long wordlist and their entropy in bits
+func Passphrase(policy pc.PassphrasePolicy) (string, float64, error) {
+	phrase, entropy, err := pc.GeneratePassphrase(policy)
+	if err != nil {
+		return "", 0, fmt.Errorf("could not generate passphrase: %w", err)
+	}
+	return phrase, entropy, nil
+}
+
+// Options make add and edit generate the new password instead of prompting for it.
+// The generated password is never printed.
+type Options struct {
+	// New returns the password to store
+	New func() (string, error)
+	// Copy puts the generated password on the clipboard, cleared after ClearAfter
+	Copy       bool
+	ClearAfter time.Duration
+}
+
+// Deliver copies the stored password to the clipboard if requested
+func (o *Options) Deliver(password string) error {
+	if !o.Copy {
+		return nil
+	}
+	return clip.Copy(clip.SystemClipboard, "Password", password, o.ClearAfter)
 }
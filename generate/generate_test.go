@@ -0,0 +1,33 @@
+package generate
+
+import (
+	"testing"
+
+	"github.com/jeremyphua/mypass/clip"
+)
+
+// Replace clip.SystemClipboard during a test
+func useFakeClipboard(t *testing.T) *clip.MemClipboard {
+	t.Helper()
+	c := &clip.MemClipboard{Text: "before"}
+	system := clip.SystemClipboard
+	clip.SystemClipboard = c
+	t.Cleanup(func() { clip.SystemClipboard = system })
+	return c
+}
+
+func TestDeliver(t *testing.T) {
+	c := useFakeClipboard(t)
+	if err := (&Options{}).Deliver("generated"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Text != "before" {
+		t.Fatalf("clipboard holds %q without --copy", c.Text)
+	}
+	if err := (&Options{Copy: true}).Deliver("generated"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Text != "generated" {
+		t.Fatalf("clipboard holds %q, want the generated password", c.Text)
+	}
+}
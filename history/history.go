@@ -0,0 +1,58 @@
+package history
+
+import (
+	"fmt"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/session"
+)
+
+// shown instead of the passwords, which are only revealed by show --revision
+const masked = "********"
+
+// List prints the current and previous passwords of a site, masked, most recent first
+func List(name string) error {
+	if err := io.RequireVault(); err != nil {
+		return err
+	}
+
+	v, err := session.OpenDefault()
+	if err != nil {
+		return err
+	}
+	if err = session.UnlockIndex(v); err != nil {
+		return err
+	}
+	si, err := v.Site(name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-9s %-20s %s\n", "Revision", "Replaced", "Password")
+	fmt.Printf("%-9d %-20s %s\n", 0, "current", masked)
+	for i, rev := range si.History {
+		fmt.Printf("%-9d %-20s %s\n", i+1, rev.Replaced.Local().Format("2006-01-02 15:04:05"), masked)
+	}
+	fmt.Printf("Keeping up to %d previous passwords, use mypass show %s --revision N to reveal one\n", v.HistoryLimit(), si.Name)
+	return nil
+}
+
+// SetLimit changes the number of previous passwords kept per site
+func SetLimit(limit int) error {
+	if err := io.RequireVault(); err != nil {
+		return err
+	}
+
+	v, err := session.OpenDefault()
+	if err != nil {
+		return err
+	}
+	if err = session.UnlockIndex(v); err != nil {
+		return err
+	}
+	if err = v.SetHistoryLimit(limit); err != nil {
+		return err
+	}
+	fmt.Printf("Keeping up to %d previous passwords per site\n", limit)
+	return nil
+}
@@ -0,0 +1,35 @@
+package importer
+
+import (
+	"path"
+	"strings"
+
+	"github.com/jeremyphua/mypass/export"
+	"github.com/jeremyphua/mypass/vault"
+)
+
+// ReadArchive reads an encrypted archive written by mypass export, asking for its passphrase.
+// Previous passwords and timestamps are not imported.
+func ReadArchive(source string) ([]Entry, error) {
+	sites, err := export.ReadArchive(source)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(sites))
+	for _, s := range sites {
+		group, name := path.Split(s.Name)
+		e := Entry{
+			Group:    strings.TrimSuffix(group, "/"),
+			Name:     name,
+			Username: s.Username,
+			Password: s.Password,
+			URL:      s.URL,
+			Notes:    s.Notes,
+		}
+		for _, f := range s.Fields {
+			e.Fields = append(e.Fields, vault.FieldInput{Name: f.Name, Value: f.Value, Secret: f.Secret})
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/jeremyphua/mypass/vault"
+)
+
+// Bitwarden item types
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+)
+
+// Bitwarden custom field types
+const (
+	bitwardenFieldText    = 0
+	bitwardenFieldHidden  = 1
+	bitwardenFieldBoolean = 2
+)
+
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Folders   []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"folders"`
+	Items []struct {
+		FolderID string `json:"folderId"`
+		Type     int    `json:"type"`
+		Name     string `json:"name"`
+		Notes    string `json:"notes"`
+		Login    struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			TOTP     string `json:"totp"`
+			URIs     []struct {
+				URI string `json:"uri"`
+			} `json:"uris"`
+		} `json:"login"`
+		Fields []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+			Type  int    `json:"type"`
+		} `json:"fields"`
+	} `json:"items"`
+}
+
+// ReadBitwardenJSON reads an unencrypted Bitwarden JSON export.
+// Logins and secure notes are imported, cards and identities are skipped.
+func ReadBitwardenJSON(source string) ([]Entry, error) {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("could not read Bitwarden export: %w", err)
+	}
+	var export bitwardenExport
+	if err = json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("could not parse Bitwarden export: %w", err)
+	}
+	if export.Encrypted {
+		return nil, fmt.Errorf("Bitwarden export is encrypted, export it as unencrypted JSON")
+	}
+
+	folders := map[string]string{}
+	for _, folder := range export.Folders {
+		folders[folder.ID] = folder.Name
+	}
+	var entries []Entry
+	for _, item := range export.Items {
+		if item.Type != bitwardenLogin && item.Type != bitwardenSecureNote {
+			fmt.Fprintf(os.Stderr, "skip    %s: only logins and secure notes are imported\n", item.Name)
+			continue
+		}
+		e := Entry{
+			Group:    folders[item.FolderID],
+			Name:     item.Name,
+			Username: item.Login.Username,
+			Password: item.Login.Password,
+			Notes:    item.Notes,
+		}
+		for i, uri := range item.Login.URIs {
+			if i == 0 {
+				e.URL = uri.URI
+			} else {
+				e.Fields = append(e.Fields, vault.FieldInput{Name: "url" + strconv.Itoa(i+1), Value: uri.URI})
+			}
+		}
+		if item.Login.TOTP != "" {
+			e.Fields = append(e.Fields, vault.FieldInput{Name: "totp", Value: item.Login.TOTP, Secret: true})
+		}
+		for _, field := range item.Fields {
+			switch field.Type {
+			case bitwardenFieldText, bitwardenFieldBoolean:
+				e.Fields = append(e.Fields, vault.FieldInput{Name: field.Name, Value: field.Value})
+			case bitwardenFieldHidden:
+				e.Fields = append(e.Fields, vault.FieldInput{Name: field.Name, Value: field.Value, Secret: true})
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,47 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jeremyphua/mypass/vault"
+)
+
+func TestReadBitwardenJSON(t *testing.T) {
+	source := writeSource(t, "bitwarden.json", `{
+  "encrypted": false,
+  "folders": [{"id": "f1", "name": "Work"}],
+  "items": [
+    {"folderId": "f1", "type": 1, "name": "github", "notes": "2fa on",
+     "login": {"username": "jp", "password": "pw1", "totp": "JBSWY3DP",
+               "uris": [{"uri": "https://github.com"}, {"uri": "https://gist.github.com"}]},
+     "fields": [{"name": "pin", "value": "1234", "type": 1},
+                {"name": "team", "value": "core", "type": 0},
+                {"name": "linked", "value": null, "type": 3}]},
+    {"folderId": null, "type": 2, "name": "wifi", "notes": "hunter2"},
+    {"folderId": null, "type": 3, "name": "visa"}
+  ]
+}`)
+	got, err := ReadBitwardenJSON(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Group: "Work", Name: "github", Username: "jp", Password: "pw1", URL: "https://github.com", Notes: "2fa on",
+			Fields: []vault.FieldInput{
+				{Name: "url2", Value: "https://gist.github.com"},
+				{Name: "totp", Value: "JBSWY3DP", Secret: true},
+				{Name: "pin", Value: "1234", Secret: true},
+				{Name: "team", Value: "core"},
+			}},
+		// the card is skipped
+		{Name: "wifi", Notes: "hunter2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ReadBitwardenJSON() = %+v, want %+v", got, want)
+	}
+
+	if _, err = ReadBitwardenJSON(writeSource(t, "bitwarden.json", `{"encrypted": true, "items": []}`)); err == nil {
+		t.Fatal("ReadBitwardenJSON() of an encrypted export succeeded")
+	}
+}
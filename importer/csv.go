@@ -0,0 +1,97 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jeremyphua/mypass/vault"
+)
+
+// Header names used by the CSV exports of Bitwarden, KeePass, KeePassXC, LastPass and browsers
+var csvColumns = map[string][]string{
+	"group":    {"group", "folder", "grouping"},
+	"name":     {"name", "title"},
+	"username": {"username", "login_username", "user name", "user", "login"},
+	"password": {"password", "login_password"},
+	"url":      {"url", "login_uri", "uri", "website"},
+	"notes":    {"notes", "extra", "comments"},
+	"totp":     {"totp", "login_totp"},
+	"fields":   {"fields"},
+}
+
+// ReadCSV reads a CSV export with a header row. Columns are matched by name,
+// the group column may hold nested folders separated by slashes.
+func ReadCSV(source string) ([]Entry, error) {
+	f, err := os.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("could not open CSV export: %w", err)
+	}
+	defer f.Close()
+
+	// the byte order mark written by Excel and some exports would make a quoted first header invalid
+	br := bufio.NewReader(f)
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\ufeff" {
+		br.Discard(3)
+	}
+	r := csv.NewReader(br)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read CSV export: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := map[string]int{}
+	for i, header := range records[0] {
+		header = strings.ToLower(strings.TrimSpace(header))
+		for column, names := range csvColumns {
+			for _, name := range names {
+				if _, found := columns[column]; !found && header == name {
+					columns[column] = i
+				}
+			}
+		}
+	}
+	if _, ok := columns["password"]; !ok {
+		return nil, fmt.Errorf("CSV export has no password column")
+	}
+
+	var entries []Entry
+	for _, record := range records[1:] {
+		value := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return record[i]
+		}
+		e := Entry{
+			Group:    strings.ReplaceAll(value("group"), "\\", "/"),
+			Name:     value("name"),
+			Username: value("username"),
+			Password: value("password"),
+			URL:      value("url"),
+			Notes:    value("notes"),
+		}
+		// KeePassXC puts every entry below its root group
+		if e.Group == "Root" || strings.HasPrefix(e.Group, "Root/") {
+			e.Group = strings.TrimPrefix(e.Group[len("Root"):], "/")
+		}
+		if totp := value("totp"); totp != "" {
+			e.Fields = append(e.Fields, vault.FieldInput{Name: "totp", Value: totp, Secret: true})
+		}
+		// Bitwarden puts custom fields in one column, a "name: value" line each
+		for _, line := range strings.Split(value("fields"), "\n") {
+			if name, v, ok := strings.Cut(line, ": "); ok {
+				e.Fields = append(e.Fields, vault.FieldInput{Name: name, Value: v})
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jeremyphua/mypass/vault"
+)
+
+// Write content to a file of a temporary folder and return its path
+func writeSource(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Entry
+	}{
+		{
+			name: "keepassxc",
+			content: "\ufeff\"Group\",\"Title\",\"Username\",\"Password\",\"URL\",\"Notes\",\"TOTP\"\n" +
+				"\"Root/Money\",\"ocbc\",\"jeremy\",\"pw1\",\"https://ocbc.com\",\"a note\",\"otpauth://totp/x\"\n" +
+				"\"Root\",\"mail\",\"me\",\"pw2\",\"\",\"\",\"\"\n",
+			want: []Entry{
+				{Group: "Money", Name: "ocbc", Username: "jeremy", Password: "pw1", URL: "https://ocbc.com", Notes: "a note",
+					Fields: []vault.FieldInput{{Name: "totp", Value: "otpauth://totp/x", Secret: true}}},
+				{Name: "mail", Username: "me", Password: "pw2"},
+			},
+		},
+		{
+			name: "bitwarden",
+			content: "folder,favorite,type,name,notes,fields,reprompt,login_uri,login_username,login_password,login_totp\n" +
+				"Work,,login,github,,\"pin: 1234\nurl: https://old.example.com\",0,https://github.com,jp,pw3,\n",
+			want: []Entry{
+				{Group: "Work", Name: "github", Username: "jp", Password: "pw3", URL: "https://github.com",
+					Fields: []vault.FieldInput{{Name: "pin", Value: "1234"}, {Name: "url", Value: "https://old.example.com"}}},
+			},
+		},
+		{
+			name: "lastpass",
+			content: "url,username,password,totp,extra,name,grouping,fav\n" +
+				"https://bank.com,user,pw4,,,bank,Finance\\Cards,0\n",
+			want: []Entry{
+				{Group: "Finance/Cards", Name: "bank", Username: "user", Password: "pw4", URL: "https://bank.com"},
+			},
+		},
+		{name: "empty", content: "", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadCSV(writeSource(t, "export.csv", tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ReadCSV() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ReadCSV(writeSource(t, "export.csv", "name,username\nocbc,jeremy\n")); err == nil {
+		t.Fatal("ReadCSV() of an export without passwords succeeded")
+	}
+}
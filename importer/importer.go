@@ -0,0 +1,253 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/session"
+	"github.com/jeremyphua/mypass/vault"
+)
+
+// Entry is a credential read from another password manager
+type Entry struct {
+	// Group is the folder of the entry, slash separated
+	Group    string
+	Name     string
+	Username string
+	Password string
+	URL      string
+	Notes    string
+	Fields   []vault.FieldInput
+}
+
+// Reader reads the entries of an export file or store at source
+type Reader func(source string) ([]Entry, error)
+
+// Formats maps the values of import --format to their readers
+var Formats = map[string]Reader{
+	"csv":            ReadCSV,
+	"bitwarden-json": ReadBitwardenJSON,
+	"keepass-xml":    ReadKeePassXML,
+	"mypass-export":  ReadArchive,
+	"passgo":         ReadPassgo,
+	"pass":           ReadPass,
+}
+
+// defaultSources are where the stores of the formats that have one are found when no path is given
+var defaultSources = map[string]func() (string, error){
+	"passgo": DefaultPassgoDir,
+	"pass":   DefaultPassDir,
+}
+
+// FormatNames returns the supported formats, sorted
+func FormatNames() []string {
+	names := make([]string, 0, len(Formats))
+	for name := range Formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// What to do with an entry whose name is already taken
+const (
+	Skip      = "skip"
+	Overwrite = "overwrite"
+	Rename    = "rename"
+)
+
+// Strategies are the values of import --on-duplicate
+var Strategies = []string{Skip, Overwrite, Rename}
+
+// ErrImportFailed is returned when some entries could not be imported
+var ErrImportFailed = errors.New("some entries could not be imported")
+
+// Options of Import
+type Options struct {
+	// OnDuplicate is Skip, Overwrite or Rename
+	OnDuplicate string
+	// Group is prepended to the group of every entry
+	Group string
+	// DryRun only reports what would be imported
+	DryRun bool
+}
+
+// Import reads the entries of source in format and adds them to the vault,
+// sealing every password like mypass add does. The passgo and pass formats
+// read their default store when source is empty.
+func Import(format, source string, opts Options) error {
+	read, ok := Formats[format]
+	if !ok {
+		return fmt.Errorf("unknown format %s, use one of %s", format, strings.Join(FormatNames(), ", "))
+	}
+	if source == "" {
+		defaultSource, ok := defaultSources[format]
+		if !ok {
+			return fmt.Errorf("the %s format needs the path of the export file", format)
+		}
+		var err error
+		if source, err = defaultSource(); err != nil {
+			return err
+		}
+	}
+	switch opts.OnDuplicate {
+	case Skip, Overwrite, Rename:
+	default:
+		return fmt.Errorf("unknown duplicate strategy %s, use one of %s", opts.OnDuplicate, strings.Join(Strategies, ", "))
+	}
+
+	if err := io.RequireVault(); err != nil {
+		return err
+	}
+	v, err := session.OpenDefault()
+	if err != nil {
+		return err
+	}
+	if err = session.UnlockIndex(v); err != nil {
+		return err
+	}
+
+	entries, err := read(source)
+	if err != nil {
+		return err
+	}
+	return importEntries(v, entries, opts)
+}
+
+// Add the entries to the vault v, whose index is unlocked
+func importEntries(v *vault.Vault, entries []Entry, opts Options) error {
+	sites, err := v.List()
+	if err != nil {
+		return err
+	}
+	taken := map[string]bool{}
+	for _, si := range sites {
+		taken[si.Name] = true
+	}
+
+	var added, overwritten, renamed, skipped, failed int
+	for _, e := range entries {
+		name, err := siteName(opts.Group, e.Group, e.Name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed  %s: %s\n", filepath.ToSlash(filepath.Join(e.Group, e.Name)), err.Error())
+			failed++
+			continue
+		}
+		action := "add"
+		if taken[name] {
+			switch opts.OnDuplicate {
+			case Skip:
+				fmt.Printf("skip    %s: %s\n", name, io.ErrDuplicateSite.Error())
+				skipped++
+				continue
+			case Overwrite:
+				action = "overwrite"
+			case Rename:
+				newName := freeName(name, taken)
+				fmt.Printf("rename  %s -> %s\n", name, newName)
+				name, action = newName, "rename"
+			}
+		}
+		e.Fields = importFields(name, e.Fields)
+		if !opts.DryRun {
+			if err = store(v, name, e, action == "overwrite"); err != nil {
+				fmt.Fprintf(os.Stderr, "failed  %s: %s\n", name, err.Error())
+				failed++
+				continue
+			}
+		}
+		taken[name] = true
+		switch action {
+		case "add":
+			fmt.Printf("add     %s\n", name)
+			added++
+		case "overwrite":
+			fmt.Printf("replace %s\n", name)
+			overwritten++
+		case "rename":
+			renamed++
+		}
+	}
+
+	prefix := "Imported"
+	if opts.DryRun {
+		prefix = "Dry run, would have imported"
+	}
+	fmt.Printf("%s %d new, %d overwritten and %d renamed sites, skipped %d duplicates\n", prefix, added, overwritten, renamed, skipped)
+	if failed > 0 {
+		return fmt.Errorf("%w: %d failed", ErrImportFailed, failed)
+	}
+	return nil
+}
+
+// Seal and store one entry, replacing the site of the same name when overwrite is set.
+// An overwritten site is rebuilt from the entry, only its history and timestamps are kept.
+func store(v *vault.Vault, name string, e Entry, overwrite bool) error {
+	si := io.SiteInfo{Name: name, Username: e.Username}
+	d := vault.Details{URL: &e.URL, Notes: &e.Notes, Fields: e.Fields}
+	if err := v.ApplyDetails(&si, d); err != nil {
+		return err
+	}
+	if overwrite {
+		return v.Put(si, e.Password)
+	}
+	return v.Add(si, e.Password)
+}
+
+// renamedFieldPrefix is put before custom fields named like the built-in values of a site
+const renamedFieldPrefix = "imported-"
+
+// Make the custom fields of an entry storable, warning about each change: fields named like
+// a built-in value get renamedFieldPrefix, fields whose name cannot be used are dropped.
+func importFields(site string, fields []vault.FieldInput) []vault.FieldInput {
+	var kept []vault.FieldInput
+	for _, f := range fields {
+		name := strings.TrimSpace(f.Name)
+		for _, reserved := range io.ReservedFieldNames {
+			if strings.EqualFold(name, reserved) {
+				f.Name = renamedFieldPrefix + name
+				fmt.Fprintf(os.Stderr, "warning %s: field %s renamed to %s, its name is reserved\n", site, name, f.Name)
+			}
+		}
+		if _, err := io.NormalizeFieldName(f.Name); err != nil {
+			fmt.Fprintf(os.Stderr, "warning %s: field dropped: %s\n", site, err.Error())
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// Build a valid site name from the group prefix, the folder and the name of an entry.
+// Characters that are not allowed in site names are replaced by dashes.
+func siteName(prefix, group, name string) (string, error) {
+	var segments []string
+	for _, part := range []string{prefix, group} {
+		for _, segment := range strings.Split(filepath.ToSlash(part), "/") {
+			if segment = io.CleanSiteSegment(segment); segment != "" {
+				segments = append(segments, segment)
+			}
+		}
+	}
+	// slashes in the name of an entry are part of its name, not groups
+	name = io.CleanSiteSegment(strings.ReplaceAll(name, "/", "-"))
+	if name == "" {
+		name = "unnamed"
+	}
+	return io.NormalizeSiteName(strings.Join(append(segments, name), "/"))
+}
+
+// First of name-2, name-3, ... that is not taken
+func freeName(name string, taken map[string]bool) string {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/vault"
+)
+
+const testPassword = "master password"
+
+func TestImportFields(t *testing.T) {
+	fields := []vault.FieldInput{
+		{Name: "pin", Value: "1234"},
+		{Name: "URL", Value: "https://old.example.com"},
+		{Name: " password ", Value: "old", Secret: true},
+		{Name: "", Value: "nameless"},
+		{Name: "a=b", Value: "x"},
+	}
+	want := []vault.FieldInput{
+		{Name: "pin", Value: "1234"},
+		{Name: "imported-URL", Value: "https://old.example.com"},
+		{Name: "imported-password", Value: "old", Secret: true},
+	}
+	if got := importFields("site", fields); !reflect.DeepEqual(got, want) {
+		t.Fatalf("importFields() = %+v, want %+v", got, want)
+	}
+}
+
+func TestImportReservedFieldNames(t *testing.T) {
+	source := writeSource(t, "export.csv", "name,username,password,fields\n"+
+		"github,jp,pw1,\"notes: from the old manager\nrecovery: codes\"\n")
+	entries, err := ReadCSV(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := vault.Create(vault.NewMemStorage(), testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = importEntries(v, entries, Options{OnDuplicate: Skip}); err != nil {
+		t.Fatal(err)
+	}
+
+	si, password, err := v.Get("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password != "pw1" || si.Username != "jp" {
+		t.Errorf("github was imported with %q and %q", si.Username, password)
+	}
+	want := []io.Field{{Name: "imported-notes", Value: "from the old manager"}, {Name: "recovery", Value: "codes"}}
+	if !reflect.DeepEqual(si.Fields, want) {
+		t.Errorf("github was imported with fields %+v, want %+v", si.Fields, want)
+	}
+}
+
+func TestImportEntries(t *testing.T) {
+	entries := []Entry{
+		{Name: "github", Username: "new", Password: "pw2", URL: "https://github.com", Fields: []vault.FieldInput{{Name: "recovery", Value: "codes"}}},
+		{Name: "bank", Username: "jp", Password: "pw3"},
+	}
+	// the site already in the vault
+	old := imported{username: "old", password: "pw1", notes: "old notes", fields: []io.Field{{Name: "pin", Value: "1234"}}}
+	github := imported{username: "new", password: "pw2", url: "https://github.com", fields: []io.Field{{Name: "recovery", Value: "codes"}}}
+	bank := imported{username: "jp", password: "pw3"}
+	tests := []struct {
+		name string
+		opts Options
+		want map[string]imported
+	}{
+		{"skip", Options{OnDuplicate: Skip}, map[string]imported{"github": old, "bank": bank}},
+		// nothing of the old site but its history is kept
+		{"overwrite", Options{OnDuplicate: Overwrite}, map[string]imported{"github": github, "bank": bank}},
+		{"rename", Options{OnDuplicate: Rename}, map[string]imported{"github": old, "github-2": github, "bank": bank}},
+		{"dry run", Options{OnDuplicate: Overwrite, DryRun: true}, map[string]imported{"github": old}},
+		{"dry run rename", Options{OnDuplicate: Rename, DryRun: true}, map[string]imported{"github": old}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := vault.Create(vault.NewMemStorage(), testPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			si := io.SiteInfo{Name: "github", Username: old.username}
+			d := vault.Details{Notes: &old.notes, Fields: []vault.FieldInput{{Name: "pin", Value: "1234"}}}
+			if err = v.ApplyDetails(&si, d); err != nil {
+				t.Fatal(err)
+			}
+			if err = v.Add(si, old.password); err != nil {
+				t.Fatal(err)
+			}
+
+			if err = importEntries(v, entries, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			sites, err := v.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]imported{}
+			for _, si := range sites {
+				got[si.Name] = readImported(t, v, si.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("vault after import = %+v, want %+v", got, tt.want)
+			}
+			if tt.opts.OnDuplicate == Overwrite && !tt.opts.DryRun {
+				if si, err = v.Site("github"); err != nil || len(si.History) != 1 {
+					t.Errorf("overwritten site has history %v, %v, want the replaced password", si.History, err)
+				}
+			}
+		})
+	}
+}
+
+// What a site holds once imported
+type imported struct {
+	username, password, url, notes string
+	fields                         []io.Field
+}
+
+func readImported(t *testing.T, v *vault.Vault, name string) imported {
+	t.Helper()
+	si, password, err := v.Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes, err := v.Notes(si)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return imported{username: si.Username, password: password, url: si.URL, notes: notes, fields: si.Fields}
+}
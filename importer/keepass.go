@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jeremyphua/mypass/vault"
+)
+
+type keePassFile struct {
+	Root struct {
+		Groups []keePassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keePassGroup struct {
+	Name    string         `xml:"Name"`
+	Entries []keePassEntry `xml:"Entry"`
+	Groups  []keePassGroup `xml:"Group"`
+}
+
+type keePassEntry struct {
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value struct {
+			Text            string `xml:",chardata"`
+			Protected       string `xml:"Protected,attr"`
+			ProtectInMemory string `xml:"ProtectInMemory,attr"`
+		} `xml:"Value"`
+	} `xml:"String"`
+}
+
+// ReadKeePassXML reads a KeePass 2 XML export. The top group is the database itself,
+// the groups below it become mypass groups. The recycle bin is skipped.
+func ReadKeePassXML(source string) ([]Entry, error) {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("could not read KeePass export: %w", err)
+	}
+	var file keePassFile
+	if err = xml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("could not parse KeePass export: %w", err)
+	}
+	var entries []Entry
+	for _, root := range file.Root.Groups {
+		if err = readKeePassGroup(root, "", &entries); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+func readKeePassGroup(g keePassGroup, group string, entries *[]Entry) error {
+	for _, kpEntry := range g.Entries {
+		e := Entry{Group: group}
+		for _, s := range kpEntry.Strings {
+			// values are only protected inside the kdbx file, never in an XML export
+			if strings.EqualFold(s.Value.Protected, "true") {
+				return fmt.Errorf("KeePass export holds encrypted values, export the database as KeePass XML (2.x) instead")
+			}
+			switch s.Key {
+			case "Title":
+				e.Name = s.Value.Text
+			case "UserName":
+				e.Username = s.Value.Text
+			case "Password":
+				e.Password = s.Value.Text
+			case "URL":
+				e.URL = s.Value.Text
+			case "Notes":
+				e.Notes = s.Value.Text
+			default:
+				secret := strings.EqualFold(s.Value.ProtectInMemory, "true")
+				e.Fields = append(e.Fields, vault.FieldInput{Name: s.Key, Value: s.Value.Text, Secret: secret})
+			}
+		}
+		*entries = append(*entries, e)
+	}
+	for _, sub := range g.Groups {
+		if sub.Name == "Recycle Bin" {
+			continue
+		}
+		if err := readKeePassGroup(sub, path.Join(group, sub.Name), entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
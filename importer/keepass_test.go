@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jeremyphua/mypass/vault"
+)
+
+func TestReadKeePassXML(t *testing.T) {
+	source := writeSource(t, "keepass.xml", `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+  <Root>
+    <Group>
+      <Name>Database</Name>
+      <Entry>
+        <String><Key>Title</Key><Value>mail</Value></String>
+        <String><Key>UserName</Key><Value>me</Value></String>
+        <String><Key>Password</Key><Value ProtectInMemory="True">pw1</Value></String>
+      </Entry>
+      <Group>
+        <Name>Money</Name>
+        <Group>
+          <Name>Banks</Name>
+          <Entry>
+            <String><Key>Title</Key><Value>ocbc</Value></String>
+            <String><Key>Password</Key><Value ProtectInMemory="True">pw2</Value></String>
+            <String><Key>URL</Key><Value>https://ocbc.com</Value></String>
+            <String><Key>Notes</Key><Value>joint account</Value></String>
+            <String><Key>PIN</Key><Value ProtectInMemory="True">1234</Value></String>
+            <String><Key>Branch</Key><Value>Orchard</Value></String>
+          </Entry>
+        </Group>
+      </Group>
+      <Group>
+        <Name>Recycle Bin</Name>
+        <Entry>
+          <String><Key>Title</Key><Value>old</Value></String>
+        </Entry>
+      </Group>
+    </Group>
+  </Root>
+</KeePassFile>`)
+	got, err := ReadKeePassXML(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Name: "mail", Username: "me", Password: "pw1"},
+		{Group: "Money/Banks", Name: "ocbc", Password: "pw2", URL: "https://ocbc.com", Notes: "joint account",
+			Fields: []vault.FieldInput{{Name: "PIN", Value: "1234", Secret: true}, {Name: "Branch", Value: "Orchard"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ReadKeePassXML() = %+v, want %+v", got, want)
+	}
+
+	protected := writeSource(t, "keepass.xml", `<KeePassFile><Root><Group><Name>Database</Name><Entry>
+<String><Key>Password</Key><Value Protected="True">c2VjcmV0</Value></String>
+</Entry></Group></Root></KeePassFile>`)
+	if _, err = ReadKeePassXML(protected); err == nil {
+		t.Fatal("ReadKeePassXML() of encrypted values succeeded")
+	}
+}
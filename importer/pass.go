@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jeremyphua/mypass/vault"
+)
+
+// DefaultPassDir returns the password store of pass, $PASSWORD_STORE_DIR or ~/.password-store
+func DefaultPassDir() (string, error) {
+	if d := os.Getenv("PASSWORD_STORE_DIR"); d != "" {
+		return d, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".password-store"), nil
+}
+
+// ReadPass decrypts every .gpg file of a pass password store with gpg.
+// Following the pass conventions the first line is the password, "key: value" lines
+// become fields, with login, user and username as the username and url as the URL,
+// and the remaining lines become the notes.
+func ReadPass(source string) ([]Entry, error) {
+	var entries []Entry
+	err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// .git and the like are not part of the store
+			if path != source && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".gpg" {
+			return nil
+		}
+		rel, err := filepath.Rel(source, strings.TrimSuffix(path, ".gpg"))
+		if err != nil {
+			return err
+		}
+		out, err := decryptGPG(path)
+		if err != nil {
+			return fmt.Errorf("could not decrypt %s: %w", rel, err)
+		}
+		group, name := filepath.Split(rel)
+		e := parsePassEntry(out)
+		e.Group, e.Name = filepath.ToSlash(group), name
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read password store: %w", err)
+	}
+	return entries, nil
+}
+
+// Decrypt a file of the store, gpg asks for the passphrase through its agent
+func decryptGPG(path string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("gpg", "--quiet", "--yes", "--decrypt", path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func parsePassEntry(content string) Entry {
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(content, "\r\n", "\n"), "\n"), "\n")
+	e := Entry{Password: lines[0]}
+	var notes []string
+	for _, line := range lines[1:] {
+		key, value, ok := strings.Cut(line, ":")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			notes = append(notes, line)
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "login", "user", "username":
+			e.Username = value
+		case "url":
+			e.URL = value
+		case "otpauth":
+			// otpauth://totp/... is split at its scheme
+			e.Fields = append(e.Fields, vault.FieldInput{Name: "otpauth", Value: "otpauth:" + value, Secret: true})
+		default:
+			e.Fields = append(e.Fields, vault.FieldInput{Name: key, Value: value})
+		}
+	}
+	e.Notes = strings.TrimSpace(strings.Join(notes, "\n"))
+	return e
+}
@@ -0,0 +1,38 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jeremyphua/mypass/vault"
+)
+
+func TestParsePassEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Entry
+	}{
+		{name: "password only", content: "pw1\n", want: Entry{Password: "pw1"}},
+		{
+			name:    "conventions",
+			content: "pw2\r\nlogin: jeremy\r\nURL: https://ocbc.com\r\notpauth://totp/ocbc?secret=X\r\npin: 1234\r\n",
+			want: Entry{Password: "pw2", Username: "jeremy", URL: "https://ocbc.com", Fields: []vault.FieldInput{
+				{Name: "otpauth", Value: "otpauth://totp/ocbc?secret=X", Secret: true},
+				{Name: "pin", Value: "1234"},
+			}},
+		},
+		{
+			name:    "notes",
+			content: "pw3\nuser: me\nsecurity question: first pet\n\nno colon here\n",
+			want:    Entry{Password: "pw3", Username: "me", Notes: "security question: first pet\n\nno colon here"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePassEntry(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parsePassEntry() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/pc"
+	"golang.org/x/crypto/scrypt"
+)
+
+// masterpass and sites.json of a passgo vault
+type passgoConfig struct {
+	MasterKeyPrivSealed []byte
+	MasterPubKey        [32]byte
+	MasterPassKeySalt   [32]byte
+}
+
+type passgoSite struct {
+	PubKey   [32]byte
+	Name     string
+	FileName string
+	IsFile   bool
+}
+
+// passgoPassword asks for the master password of the passgo vault, tests replace it
+var passgoPassword = func() (string, error) {
+	return io.PromptPass("Please enter your passgo master password")
+}
+
+// DefaultPassgoDir returns where passgo keeps its vault
+func DefaultPassgoDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".passgo"), nil
+}
+
+// ReadPassgo decrypts the vault in a passgo directory with its master password.
+// passgo does not store usernames, files stored in passgo are skipped.
+func ReadPassgo(source string) ([]Entry, error) {
+	data, err := os.ReadFile(filepath.Join(source, "config"))
+	if err != nil {
+		return nil, fmt.Errorf("could not read passgo config: %w", err)
+	}
+	var config passgoConfig
+	if err = json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("could not parse passgo config: %w", err)
+	}
+	if data, err = os.ReadFile(filepath.Join(source, "sites.json")); err != nil {
+		return nil, fmt.Errorf("could not read passgo sites: %w", err)
+	}
+	var sites []passgoSite
+	if err = json.Unmarshal(data, &sites); err != nil {
+		return nil, fmt.Errorf("could not parse passgo sites: %w", err)
+	}
+
+	pass, err := passgoPassword()
+	if err != nil {
+		return nil, fmt.Errorf("could not read password: %w", err)
+	}
+	// passgo's scrypt parameters
+	key, err := scrypt.Key([]byte(pass), config.MasterPassKeySalt[:], 1<<18, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	privBytes, ok := pc.SecretboxOpen(key, config.MasterKeyPrivSealed)
+	if !ok {
+		return nil, errors.New("could not open the passgo vault, wrong passgo master password")
+	}
+	var priv [32]byte
+	copy(priv[:], privBytes)
+
+	var entries []Entry
+	for _, site := range sites {
+		if site.IsFile {
+			fmt.Fprintf(os.Stderr, "skip    %s: files stored in passgo are not imported\n", site.Name)
+			continue
+		}
+		fileName := site.FileName
+		if fileName == "" {
+			fileName = site.Name
+		}
+		sealed, err := os.ReadFile(filepath.Join(source, "encrypted", filepath.FromSlash(fileName)))
+		if err != nil {
+			return nil, fmt.Errorf("could not read passgo password of %s: %w", site.Name, err)
+		}
+		password, ok := pc.BoxOpen(sealed, &site.PubKey, &priv)
+		if !ok {
+			return nil, fmt.Errorf("could not decrypt passgo password of %s", site.Name)
+		}
+		group, name := filepath.Split(filepath.FromSlash(site.Name))
+		entries = append(entries, Entry{Group: filepath.ToSlash(group), Name: name, Password: string(password)})
+	}
+	return entries, nil
+}
@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jeremyphua/mypass/pc"
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Write a passgo vault to dir the way passgo does, with the given master password
+func writePassgoVault(t *testing.T, dir, password string) {
+	t.Helper()
+	masterPub, masterPriv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config passgoConfig
+	config.MasterPubKey = *masterPub
+	if _, err = rand.Read(config.MasterPassKeySalt[:]); err != nil {
+		t.Fatal(err)
+	}
+	key, err := scrypt.Key([]byte(password), config.MasterPassKeySalt[:], 1<<18, 8, 1, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MasterKeyPrivSealed, err = pc.SecretboxSeal(key, masterPriv[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	var sites []passgoSite
+	for _, s := range []struct{ name, fileName, password string }{
+		{name: "work/mail", password: "pw1"},
+		{name: "bank", fileName: "a1b2c3", password: "pw2"},
+	} {
+		sitePub, sitePriv, err := box.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sealed, err := pc.BoxSeal([]byte(s.password), masterPub, sitePriv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileName := s.fileName
+		if fileName == "" {
+			fileName = s.name
+		}
+		writeFile(t, filepath.Join(dir, "encrypted", filepath.FromSlash(fileName)), sealed)
+		sites = append(sites, passgoSite{PubKey: *sitePub, Name: s.name, FileName: s.fileName})
+	}
+	sites = append(sites, passgoSite{Name: "id.pdf", FileName: "d4e5f6", IsFile: true})
+	writeFile(t, filepath.Join(dir, "config"), marshal(t, config))
+	writeFile(t, filepath.Join(dir, "sites.json"), marshal(t, sites))
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func marshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// Answer the passgo master password prompt with password
+func usePassgoPassword(t *testing.T, password string) {
+	t.Helper()
+	prompt := passgoPassword
+	passgoPassword = func() (string, error) { return password, nil }
+	t.Cleanup(func() { passgoPassword = prompt })
+}
+
+func TestReadPassgo(t *testing.T) {
+	// passgo keeps its vault in ~/.passgo
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	source, err := defaultSources["passgo"]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, ".passgo"); source != want {
+		t.Fatalf("default passgo dir = %s, want %s", source, want)
+	}
+	writePassgoVault(t, source, "passgo password")
+
+	usePassgoPassword(t, "wrong password")
+	if _, err = ReadPassgo(source); err == nil {
+		t.Fatal("ReadPassgo() with the wrong master password succeeded")
+	}
+
+	usePassgoPassword(t, "passgo password")
+	got, err := ReadPassgo(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// files stored in passgo are skipped
+	want := []Entry{
+		{Group: "work/", Name: "mail", Password: "pw1"},
+		{Name: "bank", Password: "pw2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ReadPassgo() = %+v, want %+v", got, want)
+	}
+}
@@ -1,21 +1,18 @@
 package initialize
 
 import (
-	"crypto/rand"
 	"fmt"
-	"log"
 	"os"
 
 	"github.com/jeremyphua/mypass/io"
-	"github.com/jeremyphua/mypass/pc"
-	"golang.org/x/crypto/nacl/box"
+	"github.com/jeremyphua/mypass/session"
+	mvault "github.com/jeremyphua/mypass/vault"
 )
 
 var (
 	needsDir      bool
 	hasSiteFile   bool
 	hasConfigFile bool
-	hasVault      bool
 )
 
 // Initialize a new password vault in the home directory and their respective folders
@@ -24,32 +21,27 @@ var (
 // 2. config file -> C:\Users\<name of user>\.mypass\masterpass
 // 3. sites file -> C:\Users\<name of user>\.mypass\sites.json
 // 4. vault folder -> C:\Users\<name of user>\.mypass\vault
-func Init() {
+// The application dir can be moved with --vault-dir or MYPASS_DIR.
+func Init(encryptIndex bool) error {
 
 	checkDirAndFoldersExists()
 
 	// check if application dir valid
 	passDir, err := io.GetPassDir()
 	if err != nil {
-		log.Fatalf("Could not get pass dir: %s", err.Error())
+		return fmt.Errorf("could not get pass dir: %w", err)
 	}
 
 	// check if password vault dir valid
 	siteFile, err := io.GetSiteFile()
 	if err != nil {
-		log.Fatalf("Could not get site file: %s", err.Error())
+		return fmt.Errorf("could not get site file: %w", err)
 	}
 
 	// check if config file dir valid
 	configFile, err := io.GetConfigFile()
 	if err != nil {
-		log.Fatalf("Could not get config file: %s", err.Error())
-	}
-
-	// check if vault dir valid
-	vault, err := io.GetVaultFolder()
-	if err != nil {
-		log.Fatalf("Could not get vault: %s", err.Error())
+		return fmt.Errorf("could not get config file: %w", err)
 	}
 
 	/*
@@ -58,64 +50,48 @@ func Init() {
 		If this step is placed after creation of folders, there will be error when initializing folders
 		if user quits during password prompt
 	*/
-	pass, err := io.PromptPass("Please enter your password")
+	pass, err := io.PromptMasterPass("Please enter your password")
 	if err != nil {
-		log.Fatalf("Could not read password: %s", err.Error())
+		return fmt.Errorf("could not read password: %w", err)
 	}
 
 	// if password vault does not exist, create folder C:\Users\<name of user>\.mypass
 	if needsDir {
-		CreateAppDir(passDir)
+		if err = CreateAppDir(passDir); err != nil {
+			return err
+		}
 	}
 
 	// Don't accidentally delete master password file or any file with similar name
 	if hasConfigFile {
-		log.Fatalf("Master password file already found")
+		return io.ErrVaultAlreadyInitialized
 	}
 
-	CreateConfigFile(configFile)
-
 	// Create and initialize the sites.json
 	if !hasSiteFile {
-		CreateSiteFile(siteFile)
-	}
-
-	// Create vault folder if not exist
-	if !hasVault {
-		CreateVaultFolder(vault)
-	}
-
-	// kdf the master password
-	passKey, err := pc.Argon2id(pass)
-	if err != nil {
-		log.Fatalf("Error hashing password using Argon2id: %s", err.Error())
-	}
-
-	pub, priv, err := box.GenerateKey(rand.Reader)
-	if err != nil {
-		log.Fatalf("Could not generate master key pair: %s", err.Error())
+		if err = CreateSiteFile(siteFile); err != nil {
+			return err
+		}
 	}
 
-	// Encrypt master private key with master password key
-	masterPrivKeySealed, err := pc.SecretboxSeal(passKey, priv[:])
+	// Generate the master key pair and save it to masterpass, sealed with a key derived
+	// from the master password. The vault folder is created along with it.
+	v, err := mvault.Create(session.Storage(passDir), pass)
 	if err != nil {
-		log.Fatalf("Could not encrypt master key: %s", err.Error())
-	}
-
-	passConfig := io.ConfigFile{
-		MasterPassKey:       passKey,
-		MasterPrivKeySealed: masterPrivKeySealed,
-		MasterPubKey:        *pub,
+		return err
 	}
+	fmt.Printf("Successfully written config to masterpass file at: %s\n", configFile)
 
-	// Save configs to file
-	if err = passConfig.SaveFile(); err != nil {
-		log.Fatalf("Could not write to config file: %s", err.Error())
-	} else {
-		fmt.Printf("Successfully written config to masterpass file\n")
+	if encryptIndex {
+		// a new vault has no backups of its own to remove
+		if _, err = v.EncryptIndex(true, false); err != nil {
+			return err
+		}
+		fmt.Println("Successfully encrypted the index of the vault")
 	}
 
 	fmt.Println("Password Vault successfully initialized")
+	return nil
 }
 
 // Check if dir and respective folders exist and update respective variables
@@ -130,9 +106,6 @@ func checkDirAndFoldersExists() {
 			if _, err := io.SiteFileExists(); err == nil { // Check site file exists
 				hasSiteFile = true
 			}
-			if _, err := io.VaultExists(); err == nil { // Check vault folder exists
-				hasVault = true
-			}
 		}
 	} else {
 		fmt.Println(err.Error())
@@ -140,47 +113,23 @@ func checkDirAndFoldersExists() {
 
 }
 
-func CreateAppDir(passDir string) {
-	err := os.Mkdir(passDir, 0700)
-	if err != nil {
-		log.Fatalf("Could not create mypass vault: %s", err.Error())
-	} else {
-		fmt.Printf("Successfully created directory to store passwords at: %s\n", passDir)
-	}
-}
-
-// Create mconfig file with secure permission.
-// os.Create() leaves file world-readable.
-func CreateConfigFile(configFile string) {
-	config, err := os.OpenFile(configFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+func CreateAppDir(passDir string) error {
+	// the vault dir can be anywhere, create its parents as well
+	err := os.MkdirAll(passDir, 0700)
 	if err != nil {
-		log.Fatalf("Could not create passgo config: %s", err.Error())
+		return fmt.Errorf("could not create mypass vault: %w", err)
 	}
-	config.Close()
-	fmt.Printf("Successfully created config file to store configs at: %s\n", configFile)
+	fmt.Printf("Successfully created directory to store passwords at: %s\n", passDir)
+	return nil
 }
 
 // Create file, with secure permissions.
-func CreateSiteFile(siteFile string) {
-	sf, err := os.OpenFile(siteFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		log.Fatalf("Could not create site file: %s", err.Error())
-	}
+func CreateSiteFile(siteFile string) error {
 	// Initialize an empty SiteFile
 	siteFileContents := []byte("[]")
-	_, err = sf.Write(siteFileContents)
-	if err != nil {
-		log.Fatalf("Could not save site file: %s", err.Error())
+	if err := io.WriteFileAtomic(siteFile, siteFileContents, 0600); err != nil {
+		return fmt.Errorf("could not create site file: %w", err)
 	}
-	sf.Close()
 	fmt.Printf("Successfully created site file to store information at: %s\n", siteFile)
-}
-
-func CreateVaultFolder(vault string) {
-	err := os.Mkdir(vault, 0700)
-	if err != nil {
-		log.Fatalf("Could not create vault folder: %s", err.Error())
-	} else {
-		fmt.Printf("Successfully created directory to store encrypted passwords at: %s\n", vault)
-	}
+	return nil
 }
@@ -0,0 +1,73 @@
+package io
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Names of the built-in values of a site, which custom fields cannot use
+var ReservedFieldNames = []string{"username", "password", "url", "notes"}
+
+var (
+	// ErrInvalidFieldName is returned for empty or reserved custom field names
+	ErrInvalidFieldName = errors.New("invalid field name")
+	// ErrFieldNotFound is returned when a site has no field with the given name
+	ErrFieldNotFound = errors.New("field not found")
+)
+
+// Field is a custom key/value of a site. Secret fields only keep their value sealed
+// to the master public key, plain fields keep it as is.
+type Field struct {
+	Name   string
+	Value  string `json:",omitempty"`
+	Sealed []byte `json:",omitempty"`
+}
+
+// IsSecret tells whether the value of the field is sealed
+func (f Field) IsSecret() bool {
+	return f.Sealed != nil
+}
+
+// NormalizeFieldName trims a field name and rejects empty and reserved names
+func NormalizeFieldName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, "=\r\n") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidFieldName, name)
+	}
+	for _, reserved := range ReservedFieldNames {
+		if strings.EqualFold(name, reserved) {
+			return "", fmt.Errorf("%w: %s is reserved", ErrInvalidFieldName, name)
+		}
+	}
+	return name, nil
+}
+
+// Field returns the index of the custom field called name
+func (s SiteInfo) Field(name string) (int, error) {
+	for i, f := range s.Fields {
+		if f.Name == name {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("%w: %s has no field %s", ErrFieldNotFound, s.Name, name)
+}
+
+// SetField replaces the custom field with the same name, or appends it
+func (s *SiteInfo) SetField(f Field) {
+	if i, err := s.Field(f.Name); err == nil {
+		s.Fields[i] = f
+		return
+	}
+	s.Fields = append(s.Fields, f)
+}
+
+// RemoveField deletes the custom field called name
+func (s *SiteInfo) RemoveField(name string) error {
+	i, err := s.Field(name)
+	if err != nil {
+		return err
+	}
+	s.Fields = append(s.Fields[:i], s.Fields[i+1:]...)
+	return nil
+}
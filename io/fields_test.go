@@ -0,0 +1,68 @@
+package io
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeFieldName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "pin", want: "pin"},
+		{name: " security question ", want: "security question"},
+		{name: "Recovery-Codes", want: "Recovery-Codes"},
+		{name: "", wantErr: true},
+		{name: "  ", wantErr: true},
+		{name: "a=b", wantErr: true},
+		{name: "two\nlines", wantErr: true},
+		{name: "password", wantErr: true},
+		{name: "URL", wantErr: true},
+		{name: " Notes ", wantErr: true},
+		{name: "username", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeFieldName(tt.name)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidFieldName) {
+					t.Fatalf("NormalizeFieldName(%q) = %q, %v, want ErrInvalidFieldName", tt.name, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("NormalizeFieldName(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSiteFields(t *testing.T) {
+	si := SiteInfo{Name: "bank"}
+	si.SetField(Field{Name: "pin", Value: "1234"})
+	si.SetField(Field{Name: "branch", Value: "Orchard"})
+	si.SetField(Field{Name: "pin", Sealed: []byte("sealed")})
+	want := []Field{{Name: "pin", Sealed: []byte("sealed")}, {Name: "branch", Value: "Orchard"}}
+	if !reflect.DeepEqual(si.Fields, want) {
+		t.Fatalf("fields are %+v, want %+v", si.Fields, want)
+	}
+	if !si.Fields[0].IsSecret() || si.Fields[1].IsSecret() {
+		t.Error("only the sealed field is secret")
+	}
+
+	if err := si.RemoveField("pin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := si.Field("pin"); !errors.Is(err, ErrFieldNotFound) {
+		t.Fatalf("Field(pin) = %v after removing it, want ErrFieldNotFound", err)
+	}
+	if err := si.RemoveField("pin"); !errors.Is(err, ErrFieldNotFound) {
+		t.Fatalf("RemoveField(pin) = %v twice, want ErrFieldNotFound", err)
+	}
+	if i, err := si.Field("branch"); err != nil || i != 0 {
+		t.Fatalf("Field(branch) = %d, %v, want 0", i, err)
+	}
+}
@@ -0,0 +1,95 @@
+package io
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// MasterPasswordFDEnv names an inherited file descriptor to read the master password from
+const MasterPasswordFDEnv = "MYPASS_MASTER_PASSWORD_FD"
+
+var masterPasswordFile string
+var masterPassword *string
+
+// SetMasterPasswordFile makes PromptMasterPass read the master password from a file instead of the terminal
+func SetMasterPasswordFile(path string) {
+	masterPasswordFile = path
+}
+
+// HasMasterPasswordSource tells whether the master password is read from a file or
+// file descriptor rather than prompted for
+func HasMasterPasswordSource() bool {
+	return masterPasswordFile != "" || os.Getenv(MasterPasswordFDEnv) != ""
+}
+
+// PromptMasterPass returns the master password from the file set by SetMasterPasswordFile,
+// the file descriptor in MYPASS_MASTER_PASSWORD_FD, or else prompts for it on the terminal.
+// Only the first line of a file or descriptor is used.
+func PromptMasterPass(prompt string) (string, error) {
+	if masterPassword != nil {
+		return *masterPassword, nil
+	}
+	var r io.Reader
+	switch {
+	case masterPasswordFile != "":
+		f, err := os.Open(masterPasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("could not open master password file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	case os.Getenv(MasterPasswordFDEnv) != "":
+		fd, err := strconv.Atoi(os.Getenv(MasterPasswordFDEnv))
+		if err != nil || fd < 0 {
+			return "", fmt.Errorf("invalid %s: %q", MasterPasswordFDEnv, os.Getenv(MasterPasswordFDEnv))
+		}
+		f := os.NewFile(uintptr(fd), MasterPasswordFDEnv)
+		defer f.Close()
+		r = f
+	default:
+		return PromptPass(prompt)
+	}
+	// a descriptor can only be read once, keep the password for later prompts
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("could not read master password: %w", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	masterPassword = &line
+	return line, nil
+}
+
+// ReadPassStdin reads a password from the whole of stdin, without its trailing newline
+func ReadPassStdin() (string, error) {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("could not read password from stdin: %w", err)
+	}
+	pass := strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+	if pass == "" {
+		return "", errors.New("no password given on stdin")
+	}
+	return pass, nil
+}
+
+// Confirm asks a yes or no question, anything but y or yes is a no
+func Confirm(prompt string) (bool, error) {
+	answer, err := Prompt(prompt + " [y/N] ")
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// IsInteractive tells whether stdin is a terminal that can be prompted
+func IsInteractive() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd()))
+}
@@ -6,12 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/atotto/clipboard"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
@@ -19,19 +18,99 @@ const (
 	SiteFileName    = "sites.json"
 	ConfigFileName  = "masterpass"
 	VaultFolderName = "vault"
+
+	// PassDirEnv is the environment variable used to keep the vault somewhere else than the home dir
+	PassDirEnv = "MYPASS_DIR"
 )
 
+// set by SetPassDir, takes precedence over PassDirEnv
+var passDirOverride string
+
 type ConfigFile struct {
-	MasterPassKey       []byte
+	// Version of the on-disk format of the vault, 0 for vaults written before it was introduced
+	Version int `json:",omitempty"`
+	// Argon2id hash string used as the key by masterpass files created before Salt was introduced.
+	// Only kept so that those files can be migrated, it is never written for new vaults.
+	MasterPassKey []byte `json:",omitempty"`
+	Salt          []byte
+	// Argon2 are the parameters deriving the master password key from the password and Salt,
+	// pc.LegacyArgon2Params when nil
+	Argon2              *Argon2Params `json:",omitempty"`
 	MasterPrivKeySealed []byte
 	MasterPubKey        [32]byte
+	// EncryptedIndex is set when sites.json is sealed and the entries have opaque names
+	EncryptedIndex bool `json:",omitempty"`
+	// HistoryLimit is the number of previous passwords kept per site, the default when nil
+	HistoryLimit *int `json:",omitempty"`
+}
+
+// Argon2Params are the Argon2id cost parameters used to derive a key from a password.
+// They are stored next to the salt, so that new defaults do not lock out existing vaults.
+type Argon2Params struct {
+	// Memory in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// IsLegacy reports whether the master private key is still sealed with the stored hash string
+func (c *ConfigFile) IsLegacy() bool {
+	return len(c.Salt) == 0 && len(c.MasterPassKey) > 0
 }
 
+var (
+	// ErrVaultNotInitialized is returned when the pass dir, its config or sites file is missing
+	ErrVaultNotInitialized = errors.New("vault does not exist. Run mypass init")
+	// ErrVaultAlreadyInitialized is returned when initializing over an existing master password file
+	ErrVaultAlreadyInitialized = errors.New("master password file already found")
+	// ErrSiteNotFound is returned when no site in sites.json matches the given name
+	ErrSiteNotFound = errors.New("site not found")
+	// ErrDuplicateSite is returned when adding or renaming to a name that is already taken
+	ErrDuplicateSite = errors.New("could not add site with duplicate name")
+)
+
 // SiteInfo represents a single saved password entry.
+// Notes and the values of secret fields are sealed to the master public key.
 type SiteInfo struct {
 	PubKey   [32]byte
 	Name     string
 	Username string
+	URL      string  `json:",omitempty"`
+	Notes    []byte  `json:",omitempty"`
+	Fields   []Field `json:",omitempty"`
+	// History holds the previous passwords, most recent first
+	History []Revision `json:",omitempty"`
+	// zero when unknown, for sites added before they were recorded, and then left out of sites.json
+	Created         time.Time `json:",omitempty"`
+	PasswordChanged time.Time `json:",omitempty"`
+	LastShown       time.Time `json:",omitempty"`
+}
+
+// MarshalJSON leaves out the zero times, which omitempty alone does not do for a time.Time
+func (si SiteInfo) MarshalJSON() ([]byte, error) {
+	// fields of SiteInfo without this method
+	type site SiteInfo
+	return json.Marshal(struct {
+		site
+		Created         *time.Time `json:",omitempty"`
+		PasswordChanged *time.Time `json:",omitempty"`
+		LastShown       *time.Time `json:",omitempty"`
+	}{site(si), knownTime(si.Created), knownTime(si.PasswordChanged), knownTime(si.LastShown)})
+}
+
+// nil for the zero time
+func knownTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// Revision is a previous password of a site, sealed like the current one with its own site key
+type Revision struct {
+	PubKey   [32]byte
+	Sealed   []byte
+	Replaced time.Time
 }
 
 // contents of sites.json
@@ -53,9 +132,21 @@ func PassDirExists() (bool, error) {
 	return false, err
 }
 
+// SetPassDir makes every path helper resolve to d instead of the default pass dir
+func SetPassDir(d string) {
+	passDirOverride = d
+}
+
 // Returns dir of application,
 // Example: C:\Users\<name of user>\.mypass
+// The dir set with SetPassDir is used first, then the MYPASS_DIR environment variable.
 func GetPassDir() (d string, err error) {
+	if passDirOverride != "" {
+		return filepath.Abs(passDirOverride)
+	}
+	if env := os.Getenv(PassDirEnv); env != "" {
+		return filepath.Abs(env)
+	}
 	home, err := getHomeDir()
 	if err == nil {
 		d = filepath.Join(home, ".mypass")
@@ -134,6 +225,16 @@ func VaultExists() (bool, error) {
 	return false, err
 }
 
+// RequireVault returns ErrVaultNotInitialized unless the vault folder exists
+func RequireVault() error {
+	if vf, err := VaultExists(); err != nil {
+		return fmt.Errorf("could not get vault: %w", err)
+	} else if !vf {
+		return ErrVaultNotInitialized
+	}
+	return nil
+}
+
 // Get vault folder dir
 // Example: C:\Users\<name of user>\.mypass\vault
 func GetVaultFolder() (v string, err error) {
@@ -144,161 +245,62 @@ func GetVaultFolder() (v string, err error) {
 	return
 }
 
-// Add SiteInfo to vault
-func (s *SiteInfo) AddFile(fileBytes []byte, filename string) error {
-	vault, err := GetVaultFolder()
-	if err != nil {
-		return err
-	}
-	// Make sure that the file directory exists.
-	fileDirExists, err := ConfigFileExists()
-	if err != nil {
-		return err
-	}
-	if !fileDirExists {
-		err = os.Mkdir(vault, 0700)
-		if err != nil {
-			log.Fatalf("Could not create passgo encrypted file dir: %s", err.Error())
-		}
-	}
-	encFilePath := filepath.Join(vault, filename)
-	dir, _ := filepath.Split(encFilePath)
-	err = os.MkdirAll(dir, 0700)
-	if err != nil {
-		log.Fatalf("Could not create subdirectory: %s", err.Error())
-	}
-	err = ioutil.WriteFile(encFilePath, fileBytes, 0666)
-	if err != nil {
-		return err
-	}
-
-	// We still need to add this site info to the bytes.
-	return s.AddSite()
-}
-
-// AddSite is used by individual password entries to update the vault.
-func (s *SiteInfo) AddSite() (err error) {
-	siteFile := GetSites()
-	for _, si := range siteFile {
-		if s.Name == si.Name {
-			return errors.New("Could not add site with duplicate name")
+// Find returns the index of the site with the given name
+func (s SiteFile) Find(name string) (int, error) {
+	for index, si := range s {
+		if si.Name == name {
+			return index, nil
 		}
 	}
-	siteFile = append(siteFile, *s)
-	return UpdateSiteFile(siteFile)
+	return -1, fmt.Errorf("%w: %s", ErrSiteNotFound, name)
 }
 
-// Returns SiteFile which is a slice of SiteInfo
-func GetSites() (s SiteFile) {
-	si, err := GetSiteFile()
+// WriteFileAtomic writes data to a temporary file in the same directory, flushes it to disk
+// and renames it over path, so that readers only ever see the old or the new contents
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	dir, name := filepath.Split(path)
+	tmp, err := ioutil.TempFile(dir, "."+name+".tmp")
 	if err != nil {
-		log.Fatalf("Could not get site file: %s", err.Error())
+		return err
 	}
-	siteFileContents, err := ioutil.ReadFile(si)
-	if err != nil {
-		if os.IsNotExist(err) {
-			log.Fatalf("Could not open site file. Run mypass init.: %s", err.Error())
+	tmpName := tmp.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tmpName)
 		}
-		log.Fatalf("Could not read site file: %s", err.Error())
-	}
-	err = json.Unmarshal(siteFileContents, &s)
-	if err != nil {
-		log.Fatalf("Could not unmarshal site info: %s", err.Error())
-	}
-	return
-}
-
-// UpdateSiteFile is used to replace the current sites.json.
-func UpdateSiteFile(s SiteFile) (err error) {
-	si, err := GetSiteFile()
-	if err != nil {
-		log.Fatalf("Could not get site file: %s", err.Error())
-	}
-	siteFileContents, err := json.MarshalIndent(s, "", "\t")
-	if err != nil {
-		log.Fatalf("Could not marshal site info: %s", err.Error())
-	}
-
-	// Write the site with the newly appended site to the file.
-	err = ioutil.WriteFile(si, siteFileContents, 0666)
-	return
-}
-
-func (c *ConfigFile) SaveFile() (err error) {
-	if exists, err := ConfigFileExists(); err != nil {
-		log.Fatalf("Could not find config file: %s", err.Error())
-	} else if !exists {
-		log.Fatalf("pass config could not be found %s", err.Error())
-	}
-	cBytes, err := json.MarshalIndent(c, "", "\t")
-	if err != nil {
-		log.Fatalf("Could not marshal config file: %s", err.Error())
-	}
-	cfg, err := GetConfigFile()
-	if err != nil {
-		log.Fatalf("Could not get config file: %s", err.Error())
-	}
-	err = ioutil.WriteFile(cfg, cBytes, 0666)
-	return
-}
-
-func UpdateVaultFile(path string, sealedPass []byte) (err error) {
-	vault, err := GetVaultFolder()
-	if err != nil {
-		log.Fatalf("Could not get vault path: %s", err)
-	}
-	filepath := filepath.Join(vault, path)
-	err = ioutil.WriteFile(filepath, sealedPass, 0666)
-	return
-}
-
-func UpdateFileName(oldSiteName, newSiteName string) {
-	vault, err := GetVaultFolder()
-	if err != nil {
-		log.Fatalf("Could not get vault path: %s", err)
-	}
-	oldFilePath := filepath.Join(vault, oldSiteName)
-	fileContent, err := ioutil.ReadFile(oldFilePath)
-	if err != nil {
-		log.Fatalf("Could not read site file: %s", err.Error())
-	}
-	err = os.Remove(oldFilePath)
-	if err != nil {
-		log.Fatalf("Could not remove file: %s", err.Error())
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
 	}
-	err = createNewVault(fileContent, newSiteName)
-	if err != nil {
-		log.Fatalf("Error creating new vault folder: %s", err.Error())
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
 	}
-}
-
-func createNewVault(fileBytes []byte, filename string) error {
-	vault, err := GetVaultFolder()
-	if err != nil {
+	if err = tmp.Close(); err != nil {
 		return err
 	}
-	// Make sure that the file directory exists.
-	fileDirExists, err := ConfigFileExists()
-	if err != nil {
+	if err = os.Chmod(tmpName, perm); err != nil {
 		return err
 	}
-	if !fileDirExists {
-		err = os.Mkdir(vault, 0700)
-		if err != nil {
-			log.Fatalf("Could not create passgo encrypted file dir: %s", err.Error())
-		}
+	if err = os.Rename(tmpName, path); err != nil {
+		return err
 	}
-	encFilePath := filepath.Join(vault, filename)
-	dir, _ := filepath.Split(encFilePath)
-	err = os.MkdirAll(dir, 0700)
-	if err != nil {
-		log.Fatalf("Could not create subdirectory: %s", err.Error())
+	syncDir(dir)
+	return nil
+}
+
+// Flush a rename to disk. Not every platform supports syncing a directory, so errors are ignored.
+func syncDir(dir string) {
+	if dir == "" {
+		dir = "."
 	}
-	err = ioutil.WriteFile(encFilePath, fileBytes, 0666)
+	d, err := os.Open(dir)
 	if err != nil {
-		return err
+		return
 	}
-	return err
+	d.Sync()
+	d.Close()
 }
 
 func PromptPass(prompt string) (pass string, err error) {
@@ -309,20 +311,14 @@ func PromptPass(prompt string) (pass string, err error) {
 	return string(passBytes), err
 }
 
-func Prompt(prompt string) (input string) {
+func Prompt(prompt string) (input string, err error) {
 	fmt.Printf("%s", prompt)
 	reader := bufio.NewReader(os.Stdin)
-	input, err := reader.ReadString('\n')
+	input, err = reader.ReadString('\n')
 	if err != nil {
-		log.Fatal("An error occured while reading input. Please try again", err)
+		return "", fmt.Errorf("an error occured while reading input: %w", err)
 	}
 	// remove the delimeter from the string
-	input = strings.TrimSuffix(input, "\r\n")
+	input = strings.TrimRight(input, "\r\n")
 	return
 }
-
-func ToClipboard(s string) {
-	if err := clipboard.WriteAll(s); err != nil {
-		log.Fatalf("Could not copy password to clipboard: %s", err.Error())
-	}
-}
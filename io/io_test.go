@@ -0,0 +1,90 @@
+package io
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSiteInfoJSON(t *testing.T) {
+	shown := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	sites := SiteFile{
+		{Name: "old", Username: "me"},
+		{Name: "new", Username: "me", URL: "https://example.com", Fields: []Field{{Name: "pin", Value: "1234"}},
+			Created: shown.Add(-time.Hour), PasswordChanged: shown.Add(-time.Hour), LastShown: shown},
+	}
+	data, err := json.Marshal(sites)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw []map[string]interface{}
+	if err = json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"Created", "PasswordChanged", "LastShown"} {
+		if _, ok := raw[0][key]; ok {
+			t.Errorf("unknown %s is written: %s", key, data)
+		}
+		if _, ok := raw[1][key]; !ok {
+			t.Errorf("%s is missing: %s", key, data)
+		}
+	}
+	if strings.Contains(string(data), `"site"`) {
+		t.Errorf("embedded fields are nested: %s", data)
+	}
+
+	var got SiteFile
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, sites) {
+		t.Fatalf("round trip gave %+v, want %+v", got, sites)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ConfigFileName)
+	for _, content := range []string{"old", "new"} {
+		if err := WriteFileAtomic(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if data, err := os.ReadFile(path); err != nil || string(data) != content {
+			t.Fatalf("file holds %q, %v, want %q", data, err, content)
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("file has mode %o, want 600", info.Mode().Perm())
+	}
+	// no temporary file is left behind
+	if names, _ := filepath.Glob(filepath.Join(dir, "*")); len(names) != 1 {
+		t.Errorf("folder holds %v, want only %s", names, ConfigFileName)
+	}
+
+	if err = WriteFileAtomic(filepath.Join(dir, "missing", ConfigFileName), []byte("x"), 0600); err == nil {
+		t.Fatal("WriteFileAtomic() into a missing folder succeeded")
+	}
+}
+
+func TestSiteFileFind(t *testing.T) {
+	sites := SiteFile{{Name: "money/ocbc"}, {Name: "mail"}}
+	if index, err := sites.Find("mail"); err != nil || index != 1 {
+		t.Fatalf("Find(mail) = %d, %v, want 1", index, err)
+	}
+	index, err := sites.Find("money")
+	if !errors.Is(err, ErrSiteNotFound) || index != -1 {
+		t.Fatalf("Find(money) = %d, %v, want ErrSiteNotFound", index, err)
+	}
+	if !strings.Contains(err.Error(), "money") {
+		t.Errorf("error %q does not name the site", err)
+	}
+}
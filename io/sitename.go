@@ -0,0 +1,120 @@
+package io
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// maximum length of a single group or site segment, the usual file name limit
+const maxSegmentLength = 255
+
+// ErrInvalidSiteName is returned for site names that could escape the vault folder or are not portable file names
+var ErrInvalidSiteName = errors.New("invalid site name")
+
+// NormalizeSiteName validates a site path and returns it without surrounding whitespace.
+// A site path is one or more segments separated by slashes, e.g. money/ocbc, where each segment
+// only contains letters, digits and - _ . @ +, and is not . or ..
+// Absolute paths, empty segments and trailing slashes are rejected.
+func NormalizeSiteName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: name is empty", ErrInvalidSiteName)
+	}
+	if strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("%w: %s is an absolute path", ErrInvalidSiteName, name)
+	}
+	if strings.HasSuffix(name, "/") {
+		return "", fmt.Errorf("%w: %s ends with a slash", ErrInvalidSiteName, name)
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if err := validSegment(segment); err != nil {
+			return "", fmt.Errorf("%w: %s %s", ErrInvalidSiteName, name, err.Error())
+		}
+	}
+	return name, nil
+}
+
+// CheckStoredSiteName only checks that the name of a stored site stays inside the vault folder:
+// a relative path without empty, . or .. segments. Names stored before NormalizeSiteName
+// was introduced may use any other character, they have to keep working until renamed.
+func CheckStoredSiteName(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: name is empty", ErrInvalidSiteName)
+	}
+	if strings.HasPrefix(name, "/") || filepath.IsAbs(filepath.FromSlash(name)) {
+		return fmt.Errorf("%w: %s is an absolute path", ErrInvalidSiteName, name)
+	}
+	if strings.ContainsRune(name, 0) || (filepath.Separator != '/' && strings.ContainsRune(name, filepath.Separator)) {
+		return fmt.Errorf("%w: %s contains a path separator or NUL", ErrInvalidSiteName, name)
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("%w: %s contains the segment %q", ErrInvalidSiteName, name, segment)
+		}
+	}
+	return nil
+}
+
+func validSegment(segment string) error {
+	switch {
+	case segment == "":
+		return errors.New("contains an empty segment")
+	case segment == "." || segment == "..":
+		return fmt.Errorf("contains the segment %s", segment)
+	case len(segment) > maxSegmentLength:
+		return fmt.Errorf("has a segment longer than %d characters", maxSegmentLength)
+	}
+	for _, r := range segment {
+		if !validSiteRune(r) {
+			return fmt.Errorf("contains the character %q, only letters, digits and - _ . @ + are allowed", r)
+		}
+	}
+	return nil
+}
+
+func validSiteRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	case r == '-', r == '_', r == '.', r == '@', r == '+':
+		return true
+	}
+	return false
+}
+
+// CleanSiteSegment turns a group or site segment into a valid one, replacing runs of
+// characters that are not allowed with a dash. It returns "" when nothing valid is left.
+func CleanSiteSegment(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.TrimSpace(s) {
+		if validSiteRune(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	segment := strings.Trim(b.String(), "-")
+	if segment == "." || segment == ".." {
+		return ""
+	}
+	if len(segment) > maxSegmentLength {
+		segment = segment[:maxSegmentLength]
+	}
+	return segment
+}
+
+// SuggestSiteName returns a valid site name close to name, "" if none is left
+func SuggestSiteName(name string) string {
+	var segments []string
+	for _, segment := range strings.Split(name, "/") {
+		if segment = CleanSiteSegment(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,113 @@
+package io
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeSiteName(t *testing.T) {
+	long := strings.Repeat("a", maxSegmentLength)
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "ocbc", want: "ocbc"},
+		{name: "money/ocbc", want: "money/ocbc"},
+		{name: "  money/ocbc\t", want: "money/ocbc"},
+		{name: "a/b/c.d_e-f@g+h", want: "a/b/c.d_e-f@g+h"},
+		{name: ".hidden/x", want: ".hidden/x"},
+		{name: "a/" + long, want: "a/" + long},
+		{name: "", wantErr: true},
+		{name: "   ", wantErr: true},
+		{name: "/etc/passwd", wantErr: true},
+		{name: "money/", wantErr: true},
+		{name: "money//ocbc", wantErr: true},
+		{name: "../escape", wantErr: true},
+		{name: "money/../../escape", wantErr: true},
+		{name: "./ocbc", wantErr: true},
+		{name: "my bank", wantErr: true},
+		{name: `money\ocbc`, wantErr: true},
+		{name: "a\x00b", wantErr: true},
+		{name: "café", wantErr: true},
+		{name: "a/" + long + "a", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeSiteName(tt.name)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSiteName) {
+					t.Fatalf("NormalizeSiteName(%q) = %q, %v, want ErrInvalidSiteName", tt.name, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("NormalizeSiteName(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckStoredSiteName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "money/ocbc"},
+		{name: "money/my bank"},
+		{name: "café/ünïcode"},
+		{name: " spaced "},
+		{name: "", wantErr: true},
+		{name: "/etc/passwd", wantErr: true},
+		{name: "money/", wantErr: true},
+		{name: "money//ocbc", wantErr: true},
+		{name: "..", wantErr: true},
+		{name: "money/../../escape", wantErr: true},
+		{name: "./ocbc", wantErr: true},
+		{name: "a\x00b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckStoredSiteName(tt.name)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("CheckStoredSiteName(%q) = %v, want error %v", tt.name, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSiteName) {
+				t.Fatalf("CheckStoredSiteName(%q) = %v, want ErrInvalidSiteName", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestSuggestSiteName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"money/ocbc", "money/ocbc"},
+		{"money/my bank", "money/my-bank"},
+		{" work / e mail ", "work/e-mail"},
+		{"a  &  b", "a-b"},
+		{"-dashes-", "dashes"},
+		{"money//ocbc/", "money/ocbc"},
+		{"../escape", "escape"},
+		{"日本/bank", "bank"},
+		{"!!!", ""},
+		{strings.Repeat("x", maxSegmentLength+10), strings.Repeat("x", maxSegmentLength)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SuggestSiteName(tt.name)
+			if got != tt.want {
+				t.Fatalf("SuggestSiteName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+			if got == "" {
+				return
+			}
+			if _, err := NormalizeSiteName(got); err != nil {
+				t.Fatalf("suggestion %q is not valid: %v", got, err)
+			}
+		})
+	}
+}
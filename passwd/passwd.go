@@ -0,0 +1,69 @@
+package passwd
+
+import (
+	"fmt"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/session"
+)
+
+// ChangeMasterPassword re-seals the master private key with a key derived from a new master password.
+// Only masterpass is rewritten, the site passwords are sealed with the master key pair and stay untouched.
+// The backups in the vault directory still open with the old password, they are only removed when removeBackups is set.
+func ChangeMasterPassword(removeBackups bool) error {
+
+	if err := io.RequireVault(); err != nil {
+		return err
+	}
+
+	v, err := session.OpenDefault()
+	if err != nil {
+		return err
+	}
+
+	// validate current master password
+	if err = session.Unlock(v); err != nil {
+		return err
+	}
+
+	newPass, err := promptNewPassword()
+	if err != nil {
+		return err
+	}
+
+	removed, err := v.ChangePassword(newPass, removeBackups)
+	for _, path := range removed {
+		fmt.Printf("Removed backup sealed with the old master password: %s\n", path)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println("Master password successfully changed")
+	if !removeBackups {
+		fmt.Println("The backups in the vault directory still open with the old master password, remove them with --remove-backups")
+	}
+	fmt.Println("Backups written elsewhere with mypass backup still open with the old master password, delete them")
+	return nil
+}
+
+// Prompt for the new master password twice until both entries match
+func promptNewPassword() (string, error) {
+	for {
+		pass, err := io.PromptPass("Please enter your new master password")
+		if err != nil {
+			return "", fmt.Errorf("could not read password: %w", err)
+		}
+		if pass == "" {
+			fmt.Println("Master password cannot be empty. Please try again.")
+			continue
+		}
+		confirm, err := io.PromptPass("Please confirm your new master password")
+		if err != nil {
+			return "", fmt.Errorf("could not read password: %w", err)
+		}
+		if pass == confirm {
+			return pass, nil
+		}
+		fmt.Println("Passwords do not match. Please try again.")
+	}
+}
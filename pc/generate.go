@@ -0,0 +1,195 @@
+package pc
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	// password length
+	pwLength = 20
+
+	upperCase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	lowerCase = "abcdefghijklmnopqrstuvwxyz"
+	digits    = "0123456789"
+
+	// DefaultSymbols are all printable ASCII characters that are not letters or digits
+	DefaultSymbols = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+	// AmbiguousCharacters look alike in many fonts
+	AmbiguousCharacters = "0Oo1lI|`'\""
+)
+
+// ErrUnsatisfiablePolicy is returned when no password can match a Policy
+var ErrUnsatisfiablePolicy = errors.New("password policy cannot be satisfied")
+
+// Policy describes the passwords produced by GeneratePasswordWithPolicy
+type Policy struct {
+	Length int
+	// Symbols allowed in the password, empty for letters and digits only
+	Symbols string
+	// ExcludeAmbiguous leaves out AmbiguousCharacters
+	ExcludeAmbiguous bool
+
+	// minimum number of characters of each class
+	MinUpper  int
+	MinLower  int
+	MinDigit  int
+	MinSymbol int
+}
+
+// DefaultPolicy returns 20 printable ASCII characters with at least one uppercase,
+// lowercase, digit and symbol
+func DefaultPolicy() Policy {
+	return Policy{
+		Length:    pwLength,
+		Symbols:   DefaultSymbols,
+		MinUpper:  1,
+		MinLower:  1,
+		MinDigit:  1,
+		MinSymbol: 1,
+	}
+}
+
+// characters of one class and how many of them are required
+type charClass struct {
+	name  string
+	chars string
+	min   int
+}
+
+func (p Policy) classes() []charClass {
+	classes := []charClass{
+		{"uppercase", upperCase, p.MinUpper},
+		{"lowercase", lowerCase, p.MinLower},
+		{"digit", digits, p.MinDigit},
+		{"symbol", p.Symbols, p.MinSymbol},
+	}
+	for i := range classes {
+		if p.ExcludeAmbiguous {
+			classes[i].chars = removeChars(classes[i].chars, AmbiguousCharacters)
+		}
+		classes[i].chars = uniqueChars(classes[i].chars)
+	}
+	return classes
+}
+
+// Validate returns ErrUnsatisfiablePolicy explaining why no password can match the policy
+func (p Policy) Validate() error {
+	if p.Length < 1 {
+		return fmt.Errorf("%w: length must be at least 1", ErrUnsatisfiablePolicy)
+	}
+	required := 0
+	alphabet := 0
+	for _, c := range p.classes() {
+		if c.min < 0 {
+			return fmt.Errorf("%w: minimum number of %s characters cannot be negative", ErrUnsatisfiablePolicy, c.name)
+		}
+		if c.min > 0 && c.chars == "" {
+			return fmt.Errorf("%w: %d %s characters required but none are allowed", ErrUnsatisfiablePolicy, c.min, c.name)
+		}
+		for _, r := range c.chars {
+			if r > 126 || r < 33 {
+				return fmt.Errorf("%w: %q is not a printable ASCII character", ErrUnsatisfiablePolicy, r)
+			}
+		}
+		if c.name == "symbol" && strings.ContainsAny(c.chars, upperCase+lowerCase+digits) {
+			return fmt.Errorf("%w: symbols cannot contain letters or digits", ErrUnsatisfiablePolicy)
+		}
+		required += c.min
+		alphabet += len(c.chars)
+	}
+	if required > p.Length {
+		return fmt.Errorf("%w: %d characters required but length is %d", ErrUnsatisfiablePolicy, required, p.Length)
+	}
+	if alphabet == 0 {
+		return fmt.Errorf("%w: no characters allowed", ErrUnsatisfiablePolicy)
+	}
+	return nil
+}
+
+// GeneratePassword returns a password following DefaultPolicy
+func GeneratePassword() (password string, err error) {
+	return GeneratePasswordWithPolicy(DefaultPolicy())
+}
+
+// GeneratePasswordWithPolicy returns a random password matching p.
+// The required characters of each class are drawn from that class, the rest from all allowed
+// characters, each uniformly with crypto/rand. Their positions are then shuffled uniformly.
+func GeneratePasswordWithPolicy(p Policy) (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+	classes := p.classes()
+	alphabet := ""
+	for _, c := range classes {
+		alphabet += c.chars
+	}
+
+	password := make([]byte, 0, p.Length)
+	for _, c := range classes {
+		for i := 0; i < c.min; i++ {
+			ch, err := randomChar(c.chars)
+			if err != nil {
+				return "", err
+			}
+			password = append(password, ch)
+		}
+	}
+	for len(password) < p.Length {
+		ch, err := randomChar(alphabet)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, ch)
+	}
+
+	// Fisher-Yates shuffle so the required characters are not always in front
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
+
+func randomChar(chars string) (byte, error) {
+	i, err := randomInt(len(chars))
+	if err != nil {
+		return 0, err
+	}
+	return chars[i], nil
+}
+
+// Uniform random integer in [0, n) without modulo bias
+func randomInt(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
+
+func removeChars(chars, remove string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(remove, r) {
+			return -1
+		}
+		return r
+	}, chars)
+}
+
+func uniqueChars(chars string) string {
+	var b strings.Builder
+	for _, r := range chars {
+		if !strings.ContainsRune(b.String(), r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
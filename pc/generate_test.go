@@ -0,0 +1,100 @@
+package pc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGeneratePasswordWithPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+	}{
+		{"default", DefaultPolicy()},
+		{"letters and digits", Policy{Length: 12, MinUpper: 1, MinLower: 1, MinDigit: 1}},
+		{"exclude ambiguous", Policy{Length: 30, Symbols: DefaultSymbols, ExcludeAmbiguous: true, MinDigit: 5, MinSymbol: 5}},
+		{"custom symbols", Policy{Length: 8, Symbols: "@#@", MinSymbol: 3}},
+		{"only required characters", Policy{Length: 6, MinUpper: 2, MinLower: 2, MinDigit: 2}},
+		{"single character", Policy{Length: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classes := tt.policy.classes()
+			for i := 0; i < 200; i++ {
+				password, err := GeneratePasswordWithPolicy(tt.policy)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(password) != tt.policy.Length {
+					t.Fatalf("%q has length %d, want %d", password, len(password), tt.policy.Length)
+				}
+				counts := make([]int, len(classes))
+			chars:
+				for _, r := range password {
+					for j, c := range classes {
+						if strings.ContainsRune(c.chars, r) {
+							counts[j]++
+							continue chars
+						}
+					}
+					t.Fatalf("%q contains %q which the policy does not allow", password, r)
+				}
+				for j, c := range classes {
+					if counts[j] < c.min {
+						t.Fatalf("%q has %d %s characters, want at least %d", password, counts[j], c.name, c.min)
+					}
+				}
+				if tt.policy.ExcludeAmbiguous && strings.ContainsAny(password, AmbiguousCharacters) {
+					t.Fatalf("%q contains ambiguous characters", password)
+				}
+			}
+		})
+	}
+}
+
+func TestGeneratePasswordUsesWholeAlphabet(t *testing.T) {
+	// with 62 characters, missing one in 2000 uniform draws has a probability below 1e-12
+	password, err := GeneratePasswordWithPolicy(Policy{Length: 2000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range upperCase + lowerCase + digits {
+		if !strings.ContainsRune(password, r) {
+			t.Errorf("%q was never drawn", r)
+		}
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr bool
+	}{
+		{"default", DefaultPolicy(), false},
+		{"no symbols", Policy{Length: 10, MinUpper: 1}, false},
+		{"zero length", Policy{Length: 0}, true},
+		{"negative minimum", Policy{Length: 10, MinLower: -1}, true},
+		{"more required than length", Policy{Length: 3, MinUpper: 2, MinDigit: 2}, true},
+		{"symbols required but none allowed", Policy{Length: 10, MinSymbol: 1}, true},
+		{"all symbols ambiguous", Policy{Length: 10, Symbols: "|`", ExcludeAmbiguous: true, MinSymbol: 1}, true},
+		{"letters as symbols", Policy{Length: 10, Symbols: "a!"}, true},
+		{"non printable symbol", Policy{Length: 10, Symbols: "! "}, true},
+		{"non ASCII symbol", Policy{Length: 10, Symbols: "!€"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrUnsatisfiablePolicy) {
+				t.Fatalf("Validate() = %v, want ErrUnsatisfiablePolicy", err)
+			}
+			if _, err = GeneratePasswordWithPolicy(tt.policy); tt.wantErr != (err != nil) {
+				t.Fatalf("GeneratePasswordWithPolicy() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
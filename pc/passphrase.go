@@ -0,0 +1,100 @@
+package pc
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// EFF long wordlist, one "dice rolls<TAB>word" per line
+// https://www.eff.org/files/2016/07/18/eff_large_wordlist.txt
+//
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist string
+
+var (
+	wordlistOnce sync.Once
+	wordlist     []string
+)
+
+// PassphrasePolicy describes the passphrases produced by GeneratePassphrase
+type PassphrasePolicy struct {
+	Words     int
+	Separator string
+	// Capitalize uppercases the first letter of every word
+	Capitalize bool
+	// Digit appends a random digit to a random word
+	Digit bool
+}
+
+// DefaultPassphrasePolicy returns six lowercase words separated by dashes
+func DefaultPassphrasePolicy() PassphrasePolicy {
+	return PassphrasePolicy{
+		Words:     6,
+		Separator: "-",
+	}
+}
+
+// Words of the EFF long wordlist
+func words() []string {
+	wordlistOnce.Do(func() {
+		for _, line := range strings.Split(strings.TrimSpace(effLargeWordlist), "\n") {
+			fields := strings.Fields(line)
+			wordlist = append(wordlist, fields[len(fields)-1])
+		}
+	})
+	return wordlist
+}
+
+// Words of the EFF long wordlist not containing separator. A few words such as yo-yo contain
+// a dash, they would make a passphrase split into other words and overstate its entropy.
+func wordsWithout(separator string) []string {
+	all := words()
+	if separator == "" {
+		return all
+	}
+	var list []string
+	for _, w := range all {
+		if !strings.Contains(w, separator) {
+			list = append(list, w)
+		}
+	}
+	return list
+}
+
+// GeneratePassphrase returns words picked uniformly from the EFF long wordlist, leaving out
+// the words containing the separator, along with the entropy of the passphrase in bits
+func GeneratePassphrase(p PassphrasePolicy) (passphrase string, entropy float64, err error) {
+	if p.Words < 1 {
+		return "", 0, fmt.Errorf("%w: at least 1 word is required", ErrUnsatisfiablePolicy)
+	}
+	list := wordsWithout(p.Separator)
+	chosen := make([]string, p.Words)
+	for i := range chosen {
+		n, err := randomInt(len(list))
+		if err != nil {
+			return "", 0, err
+		}
+		chosen[i] = list[n]
+		if p.Capitalize {
+			chosen[i] = strings.ToUpper(chosen[i][:1]) + chosen[i][1:]
+		}
+	}
+	entropy = float64(p.Words) * math.Log2(float64(len(list)))
+
+	if p.Digit {
+		word, err := randomInt(p.Words)
+		if err != nil {
+			return "", 0, err
+		}
+		digit, err := randomInt(len(digits))
+		if err != nil {
+			return "", 0, err
+		}
+		chosen[word] += digits[digit : digit+1]
+		entropy += math.Log2(float64(p.Words * len(digits)))
+	}
+	return strings.Join(chosen, p.Separator), entropy, nil
+}
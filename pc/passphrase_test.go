@@ -0,0 +1,152 @@
+package pc
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestWordlist(t *testing.T) {
+	list := words()
+	if len(list) != 7776 {
+		t.Fatalf("wordlist has %d words, want 6^5", len(list))
+	}
+	seen := make(map[string]bool, len(list))
+	for _, w := range list {
+		if seen[w] {
+			t.Errorf("%q is listed twice", w)
+		}
+		seen[w] = true
+	}
+}
+
+// The default separator splits a passphrase back into its words
+func TestWordsWithoutDefaultSeparator(t *testing.T) {
+	separator := DefaultPassphrasePolicy().Separator
+	list := wordsWithout(separator)
+	// drop-down, felt-tip, t-shirt and yo-yo
+	if len(list) != 7772 {
+		t.Errorf("%d words are left without %q, want 7772", len(list), separator)
+	}
+	for _, w := range list {
+		if strings.Contains(w, separator) {
+			t.Errorf("%q contains the default separator %q", w, separator)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		phrase, _, err := GeneratePassphrase(DefaultPassphrasePolicy())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(strings.Split(phrase, separator)); n != 6 {
+			t.Fatalf("%q splits into %d words, want 6", phrase, n)
+		}
+	}
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	wordEntropy := math.Log2(7776)
+	// the 4 words with a dash are left out
+	dashedEntropy := math.Log2(7772)
+	tests := []struct {
+		name        string
+		policy      PassphrasePolicy
+		wantEntropy float64
+	}{
+		{"default", DefaultPassphrasePolicy(), 6 * dashedEntropy},
+		{"single word", PassphrasePolicy{Words: 1, Separator: "-"}, dashedEntropy},
+		{"capitalized", PassphrasePolicy{Words: 4, Separator: " ", Capitalize: true}, 4 * wordEntropy},
+		{"with digit", PassphrasePolicy{Words: 5, Separator: ".", Digit: true}, 5*wordEntropy + math.Log2(50)},
+		{"no separator", PassphrasePolicy{Words: 3}, 3 * wordEntropy},
+	}
+	list := make(map[string]bool)
+	for _, w := range words() {
+		list[w] = true
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				phrase, entropy, err := GeneratePassphrase(tt.policy)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if math.Abs(entropy-tt.wantEntropy) > 1e-9 {
+					t.Fatalf("entropy = %f, want %f", entropy, tt.wantEntropy)
+				}
+				if !isPassphrase(phrase, tt.policy.Words, tt.policy, list) {
+					t.Fatalf("%q is not %d words of the wordlist following %+v", phrase, tt.policy.Words, tt.policy)
+				}
+			}
+		})
+	}
+}
+
+// isPassphrase tells whether phrase is n words of list joined as described by p.
+// Every split is tried since the separator may also appear within a word, like the dash of yo-yo.
+func isPassphrase(phrase string, n int, p PassphrasePolicy, list map[string]bool) bool {
+	if n == 0 {
+		return phrase == "" && !p.Digit
+	}
+	for end := 1; end <= len(phrase); end++ {
+		word, rest := phrase[:end], phrase[end:]
+		if n > 1 {
+			if !strings.HasPrefix(rest, p.Separator) {
+				continue
+			}
+			rest = rest[len(p.Separator):]
+		} else if rest != "" {
+			continue
+		}
+		next := p
+		if last := rune(word[len(word)-1]); unicode.IsDigit(last) {
+			if !p.Digit || len(word) == 1 {
+				continue
+			}
+			word = word[:len(word)-1]
+			next.Digit = false
+		}
+		if p.Capitalize {
+			if !unicode.IsUpper(rune(word[0])) {
+				continue
+			}
+			word = strings.ToLower(word[:1]) + word[1:]
+		}
+		if list[word] && isPassphrase(rest, n-1, next, list) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIsPassphrase(t *testing.T) {
+	list := map[string]bool{"yo-yo": true, "abacus": true}
+	tests := []struct {
+		phrase string
+		n      int
+		policy PassphrasePolicy
+		want   bool
+	}{
+		{"yo-yo-abacus", 2, PassphrasePolicy{Separator: "-"}, true},
+		{"yo-yo-abacus", 3, PassphrasePolicy{Separator: "-"}, false},
+		{"abacus-nope", 2, PassphrasePolicy{Separator: "-"}, false},
+		{"Abacus7 Yo-yo", 2, PassphrasePolicy{Separator: " ", Capitalize: true, Digit: true}, true},
+		{"Abacus Yo-yo", 2, PassphrasePolicy{Separator: " ", Capitalize: true, Digit: true}, false},
+		{"abacus7", 1, PassphrasePolicy{}, false},
+		{"abacus Yo-yo", 2, PassphrasePolicy{Separator: " ", Capitalize: true}, false},
+	}
+	for _, tt := range tests {
+		if got := isPassphrase(tt.phrase, tt.n, tt.policy, list); got != tt.want {
+			t.Errorf("isPassphrase(%q, %d, %+v) = %v, want %v", tt.phrase, tt.n, tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestGeneratePassphraseNeedsWords(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		if _, _, err := GeneratePassphrase(PassphrasePolicy{Words: n}); !errors.Is(err, ErrUnsatisfiablePolicy) {
+			t.Errorf("GeneratePassphrase() with %d words = %v, want ErrUnsatisfiablePolicy", n, err)
+		}
+	}
+}
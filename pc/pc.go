@@ -1,63 +1,88 @@
 package pc
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
 
-	"github.com/alexedwards/argon2id"
 	"github.com/jeremyphua/mypass/io"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/nacl/box"
 	"golang.org/x/crypto/nacl/secretbox"
 )
 
-const (
-	// password length
-	pwLength = 20
+// ErrWrongMasterPassword is returned when the master password does not open the master private key
+var ErrWrongMasterPassword = errors.New("wrong master password")
 
-	// ASCII bound values
-	// https://design215.com/toolbox/ascii-utf8.php
-	// Uppercase ASCII bound values
-	upperCaseLowerbound = 65
-	upperCaseUpperbound = 90
+const (
+	saltLength = 16
+	keyLength  = 32
+)
 
-	// Lowercase ASCII bound values
-	lowerCaseLowerbound = 97
-	lowerCaseUpperbound = 122
+// LegacyArgon2Params derived every key before the parameters were stored next to the salt.
+// They must never change, vaults and archives without stored parameters could not be opened anymore.
+func LegacyArgon2Params() io.Argon2Params {
+	return io.Argon2Params{Memory: 64 * 1024, Iterations: 1, Parallelism: 2}
+}
 
-	// Digit ASCII bound values
-	digitCaseLowerbound = 48
-	digitCaseUpperbound = 57
+// DefaultArgon2Params returns the parameters of new master password keys and archive passphrases
+func DefaultArgon2Params() io.Argon2Params {
+	return LegacyArgon2Params()
+}
 
-	// Symbol ASCII bound values
-	symbolGroupOneLowerbound = 33
-	symbolGroupOneUpperbound = 47
+// ErrInvalidArgon2Params is returned for stored parameters argon2 cannot or should not run with
+var ErrInvalidArgon2Params = errors.New("invalid Argon2id parameters")
 
-	symbolGroupTwoLowerbound = 58
-	symbolGroupTwoUpperbound = 64
+// maximum memory of stored parameters, 4 GiB, so that a crafted file cannot exhaust the memory
+const maxArgon2Memory = 4 * 1024 * 1024
 
-	symbolGroupThreeLowerbound = 91
-	symbolGroupThreeUpperbound = 96
+func checkArgon2Params(p io.Argon2Params) error {
+	switch {
+	case p.Iterations < 1:
+		return fmt.Errorf("%w: at least 1 iteration is required", ErrInvalidArgon2Params)
+	case p.Parallelism < 1:
+		return fmt.Errorf("%w: parallelism must be at least 1", ErrInvalidArgon2Params)
+	case p.Memory < 8*uint32(p.Parallelism):
+		return fmt.Errorf("%w: memory must be at least 8 KiB per thread", ErrInvalidArgon2Params)
+	case p.Memory > maxArgon2Memory:
+		return fmt.Errorf("%w: memory of %d KiB is above the %d KiB limit", ErrInvalidArgon2Params, p.Memory, maxArgon2Memory)
+	}
+	return nil
+}
 
-	symbolGroupFourLowerbound = 123
-	symbolGroupFourUpperbound = 126
-)
+// Generate a random salt to be stored alongside the sealed master private key
+func NewSalt() (salt []byte, err error) {
+	salt = make([]byte, saltLength)
+	_, err = rand.Read(salt)
+	return
+}
 
-var customArgon2idParams = &argon2id.Params{
-	Memory:      64 * 1024,
-	Iterations:  1,
-	Parallelism: 2,
-	SaltLength:  16,
-	KeyLength:   8,
+// Derive the 32-bytes master password key from the password and salt using Argon2id with the
+// stored params, LegacyArgon2Params when nil. The key is derived at unlock time and never written to disk
+func DeriveKey(password string, salt []byte, params *io.Argon2Params) ([]byte, error) {
+	p := LegacyArgon2Params()
+	if params != nil {
+		p = *params
+	}
+	if err := checkArgon2Params(p); err != nil {
+		return nil, err
+	}
+	return argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, keyLength), nil
 }
 
-// Wrapper around argon2id.CreateHash to create generic function to take in string input
-// Return a 32-bytes key
-func Argon2id(password string) (key []byte, err error) {
-	hash, err := argon2id.CreateHash(password, customArgon2idParams)
-	key = []byte(hash)
+// Generate a new salt, derive the master password key with params and seal the master private key with it
+func SealMasterPrivKey(password string, params io.Argon2Params, masterPrivKey *[32]byte) (salt []byte, sealed []byte, err error) {
+	if salt, err = NewSalt(); err != nil {
+		return
+	}
+	key, err := DeriveKey(password, salt, &params)
+	if err != nil {
+		return
+	}
+	sealed, err = SecretboxSeal(key, masterPrivKey[:])
 	return
 }
 
@@ -77,6 +102,9 @@ func SecretboxSeal(key []byte, message []byte) ([]byte, error) {
 // Wrapper around secretbox.Open
 // Convert key byte slice to 32-bytes
 func SecretboxOpen(key []byte, encrypted []byte) ([]byte, bool) {
+	if len(encrypted) < 24 {
+		return nil, false
+	}
 	var decryptNonce [24]byte
 	copy(decryptNonce[:], encrypted[:24])
 	var keyArr [32]byte
@@ -96,175 +124,54 @@ func BoxSeal(message []byte, pub *[32]byte, priv *[32]byte) (out []byte, err err
 
 // wrapper around box.Open
 func BoxOpen(encrypted []byte, pub *[32]byte, priv *[32]byte) ([]byte, bool) {
+	if len(encrypted) < 24 {
+		return nil, false
+	}
 	var decryptNonce [24]byte
 	copy(decryptNonce[:], encrypted[:24])
 	return box.Open(nil, encrypted[24:], &decryptNonce, pub, priv)
 }
 
-// Reencrypt new password using BoxSeal
-func ReEncrypt(s io.SiteInfo, password string) (io.SiteInfo, []byte) {
-	var c io.ConfigFile
-	pub, priv, err := box.GenerateKey(rand.Reader)
-	if err != nil {
-		log.Fatalf("Could not generate site key: %s", err.Error())
-	}
-	config, err := io.GetConfigFile()
-	if err != nil {
-		log.Fatalf("Could not get config file: %s", err.Error())
-	}
-	configContents, err := ioutil.ReadFile(config)
-	if err != nil {
-		log.Fatalf("Could not read contents of config: %s", err.Error())
-	}
-	err = json.Unmarshal(configContents, &c)
-
-	masterPub := c.MasterPubKey
-
-	passSealed, err := BoxSeal([]byte(password), &masterPub, priv)
-	if err != nil {
-		log.Fatalf("Could not seal new site password: %s", err.Error())
-	}
-
-	return io.SiteInfo{
-		Name:     s.Name,
-		PubKey:   *pub,
-		Username: s.Username,
-	}, passSealed
-
+// DeriveIndexKeys derives the key sealing an encrypted index and the key naming
+// its entries from the master private key
+func DeriveIndexKeys(masterPrivKey *[32]byte) (indexKey, nameKey []byte) {
+	return hmacSHA256(masterPrivKey[:], "mypass index"), hmacSHA256(masterPrivKey[:], "mypass entry names")
 }
 
-// Retrieve master private key
-func GetMasterPrivKey() (masterPrivKey [32]byte) {
-	pass, err := io.PromptPass("Please enter master password")
-	if err != nil {
-		log.Fatalf("Could not read password: %s", err.Error())
-	}
-
-	cfile, err := io.GetConfigFile()
-	if err != nil {
-		log.Fatalf("Could not get config file: %s", err.Error())
-	}
-
-	var c io.ConfigFile
-
-	configFileBytes, err := ioutil.ReadFile(cfile)
-	if err != nil {
-		log.Fatalf("Could not read config file: %s", err.Error())
-	}
-
-	err = json.Unmarshal(configFileBytes, &c)
-	if err != nil {
-		log.Fatalf("Could not read unmarshal config file: %s", err.Error())
-	}
-
-	validateMasterPassword(pass, string(c.MasterPassKey))
-
-	masterPrivKeySlice, ok := SecretboxOpen(c.MasterPassKey, c.MasterPrivKeySealed)
-
-	if !ok {
-		log.Fatalf("Failed to get master private key")
-	}
-
-	copy(masterPrivKey[:], masterPrivKeySlice)
-	fmt.Println("Authentication success!")
-	return
+// EntryID returns the opaque file name of the entry of a site in an encrypted index
+func EntryID(nameKey []byte, name string) string {
+	return hex.EncodeToString(hmacSHA256(nameKey, name))
 }
 
-func validateMasterPassword(input string, encryptedMasterPassword string) {
-	match, err := argon2id.ComparePasswordAndHash(input, encryptedMasterPassword)
-	if err != nil {
-		log.Fatalf("Error comparing password: %s", err.Error())
-	}
-	if !match {
-		log.Fatalf("Wrong master password")
-	}
+func hmacSHA256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
 }
 
-func GeneratePassword() (password string, err error) {
-	// make a slice of random bytes
-	letters := make([]byte, 10000)
-
-	// read random bytes
-	_, err = rand.Read(letters)
-	if err != nil {
-		return
-	}
-
-	password = ""
-	for _, letter := range letters {
-		// Check letter is in the range of printable characters
-		if letter > 32 && letter < 127 {
-			password += string(letter)
-		}
-		// If length of password reach 12, check if it is valid
-		if len(password) == pwLength {
-			if validPassword(password) {
-				return
-			}
-			// trim left character of password
-			password = password[1:]
-		}
-	}
-	return
-}
-
-func validPassword(password string) bool {
-	isUpper := false
-	isLower := false
-	isSymbol := false
-	isDigit := false
-
-	for i := 0; i < len(password); i++ {
-		if isASCIIUpper(password[i]) {
-			isUpper = true
-		}
-		if isASCIILower(password[i]) {
-			isLower = true
-		}
-		if isASCIISymbol(password[i]) {
-			isSymbol = true
-		}
-		if isASCIIDigit(password[i]) {
-			isDigit = true
-		}
-		if isUpper && isLower && isSymbol && isDigit {
-			return true
-		}
-	}
-	return false
-}
-
-func isASCIIUpper(letter byte) bool {
-	return checkBound(letter, upperCaseLowerbound, upperCaseUpperbound)
+// SealAnonymous seals message so that only the owner of the private key matching pub can open it
+func SealAnonymous(message []byte, pub *[32]byte) ([]byte, error) {
+	return box.SealAnonymous(nil, message, pub, rand.Reader)
 }
 
-func isASCIILower(letter byte) bool {
-	return checkBound(letter, lowerCaseLowerbound, lowerCaseUpperbound)
+// OpenAnonymous opens a message sealed with SealAnonymous
+func OpenAnonymous(sealed []byte, pub *[32]byte, priv *[32]byte) ([]byte, bool) {
+	return box.OpenAnonymous(nil, sealed, pub, priv)
 }
 
-func isASCIISymbol(letter byte) bool {
-	if checkBound(letter, symbolGroupOneLowerbound, symbolGroupOneUpperbound) {
-		return true
-	}
-	if checkBound(letter, symbolGroupTwoLowerbound, symbolGroupTwoUpperbound) {
-		return true
-	}
-	if checkBound(letter, symbolGroupThreeLowerbound, symbolGroupThreeUpperbound) {
-		return true
-	}
-	if checkBound(letter, symbolGroupFourLowerbound, symbolGroupFourUpperbound) {
-		return true
+// Reencrypt new password using BoxSeal
+// A fresh site key pair is generated for every password
+func ReEncrypt(s io.SiteInfo, password string, masterPub *[32]byte) (io.SiteInfo, []byte, error) {
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return s, nil, fmt.Errorf("could not generate site key: %w", err)
 	}
-	return false
-}
 
-func isASCIIDigit(letter byte) bool {
-	return checkBound(letter, digitCaseLowerbound, digitCaseUpperbound)
-}
-
-func checkBound(letter byte, lowerBound, upperBound int) bool {
-	if int(letter) >= lowerBound && int(letter) <= upperBound {
-		return true
+	passSealed, err := BoxSeal([]byte(password), masterPub, priv)
+	if err != nil {
+		return s, nil, fmt.Errorf("could not seal new site password: %w", err)
 	}
-	return false
+
+	s.PubKey = *pub
+	return s, passSealed, nil
 }
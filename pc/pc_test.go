@@ -0,0 +1,130 @@
+package pc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/jeremyphua/mypass/io"
+	"golang.org/x/crypto/nacl/box"
+)
+
+func TestDeriveKey(t *testing.T) {
+	salt := bytes.Repeat([]byte{1}, saltLength)
+	legacy := LegacyArgon2Params()
+	want, err := DeriveKey("password", salt, &legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != keyLength {
+		t.Fatalf("key has %d bytes, want %d", len(want), keyLength)
+	}
+	// vaults written before the parameters were stored must keep deriving the same key
+	if got, err := DeriveKey("password", salt, nil); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("DeriveKey() without params = %x, %v, want %x", got, err, want)
+	}
+
+	tests := []struct {
+		name    string
+		params  io.Argon2Params
+		wantErr bool
+	}{
+		{name: "more iterations", params: io.Argon2Params{Memory: 64 * 1024, Iterations: 2, Parallelism: 2}},
+		{name: "less memory", params: io.Argon2Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 2}},
+		{name: "one thread", params: io.Argon2Params{Memory: 64 * 1024, Iterations: 1, Parallelism: 1}},
+		{name: "no iterations", params: io.Argon2Params{Memory: 64 * 1024, Parallelism: 2}, wantErr: true},
+		{name: "no threads", params: io.Argon2Params{Memory: 64 * 1024, Iterations: 1}, wantErr: true},
+		{name: "too little memory", params: io.Argon2Params{Memory: 15, Iterations: 1, Parallelism: 2}, wantErr: true},
+		{name: "too much memory", params: io.Argon2Params{Memory: maxArgon2Memory + 1, Iterations: 1, Parallelism: 2}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DeriveKey("password", salt, &tt.params)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidArgon2Params) {
+					t.Fatalf("DeriveKey() = %v, want ErrInvalidArgon2Params", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(got, want) {
+				t.Fatal("different parameters derived the same key")
+			}
+		})
+	}
+}
+
+func TestSealMasterPrivKey(t *testing.T) {
+	var priv [32]byte
+	copy(priv[:], "master private key of 32 bytes!")
+	params := io.Argon2Params{Memory: 8 * 1024, Iterations: 3, Parallelism: 1}
+	salt, sealed, err := SealMasterPrivKey("password", params, &priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := DeriveKey("password", salt, &params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened, ok := SecretboxOpen(key, sealed); !ok || !bytes.Equal(opened, priv[:]) {
+		t.Fatal("the master private key does not open with the key derived from its params")
+	}
+	if key, err = DeriveKey("password", salt, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := SecretboxOpen(key, sealed); ok {
+		t.Fatal("the master private key opens with the legacy params")
+	}
+}
+
+func TestSecretbox(t *testing.T) {
+	key := bytes.Repeat([]byte{2}, 32)
+	sealed, err := SecretboxSeal(key, []byte("message"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened, ok := SecretboxOpen(key, sealed); !ok || string(opened) != "message" {
+		t.Fatalf("SecretboxOpen() = %q, %v", opened, ok)
+	}
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 1
+	other := bytes.Repeat([]byte{3}, 32)
+	// failures are reported, never a panic on short input
+	for name, tt := range map[string]struct{ key, data []byte }{
+		"wrong key": {other, sealed},
+		"tampered":  {key, tampered},
+		"short":     {key, sealed[:10]},
+		"empty":     {key, nil},
+	} {
+		if _, ok := SecretboxOpen(tt.key, tt.data); ok {
+			t.Errorf("SecretboxOpen() of %s data succeeded", name)
+		}
+	}
+}
+
+func TestBox(t *testing.T) {
+	masterPub, masterPriv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sitePub, sitePriv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := BoxSeal([]byte("pw"), masterPub, sitePriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password, ok := BoxOpen(sealed, sitePub, masterPriv); !ok || string(password) != "pw" {
+		t.Fatalf("BoxOpen() = %q, %v", password, ok)
+	}
+	if _, ok := BoxOpen(sealed[:20], sitePub, masterPriv); ok {
+		t.Fatal("BoxOpen() of short data succeeded")
+	}
+	if _, ok := BoxOpen(sealed, masterPub, masterPriv); ok {
+		t.Fatal("BoxOpen() with the wrong site key succeeded")
+	}
+}
@@ -0,0 +1,46 @@
+package rotate
+
+import (
+	"fmt"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/session"
+)
+
+// RotateKeys generates a new master key pair and re-encrypts every site in the vault with it.
+// Every site also gets a fresh key pair. The new masterpass, sites.json and vault folder are staged
+// next to the current ones and swapped in together, the old ones are restored if any step fails.
+// The backups in the vault directory hold the old keys, they are only removed when removeBackups is set.
+func RotateKeys(removeBackups bool) error {
+
+	if err := io.RequireVault(); err != nil {
+		return err
+	}
+
+	v, err := session.OpenDefault()
+	if err != nil {
+		return err
+	}
+	if err = session.Unlock(v); err != nil {
+		return err
+	}
+
+	count, removed, err := v.RotateKeys(removeBackups)
+	printRemovedBackups(removed)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Successfully rotated master key pair and re-encrypted %d sites\n", count)
+	if !removeBackups {
+		fmt.Println("The backups in the vault directory still hold the old master key, remove them with --remove-backups")
+	}
+	fmt.Println("Backups written elsewhere with mypass backup still hold the old master key, delete them")
+	return nil
+}
+
+// The backups in the vault directory were sealed with the old master key, --remove-backups removes them
+func printRemovedBackups(removed []string) {
+	for _, path := range removed {
+		fmt.Printf("Removed backup sealed with the old master key: %s\n", path)
+	}
+}
@@ -0,0 +1,71 @@
+package session
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/vault"
+)
+
+var storageOptions = vault.DefaultFileOptions()
+
+// SetStorageOptions sets how the vaults opened by the commands wait for the lock,
+// treat insecure permissions and keep rolling backups
+func SetStorageOptions(opts vault.FileOptions) {
+	storageOptions = opts
+}
+
+// Storage returns the storage of the pass dir d, with the options of the commands
+func Storage(d string) *vault.FileStorage {
+	return vault.NewFileStorage(d, storageOptions)
+}
+
+// Open opens the vault stored in s and prints the repairs and migrations done on the way to stderr
+func Open(s vault.Storage) (*vault.Vault, error) {
+	v, err := vault.Open(s)
+	if err != nil {
+		return nil, err
+	}
+	for _, notice := range v.Notices() {
+		fmt.Fprintln(os.Stderr, notice)
+	}
+	return v, nil
+}
+
+// OpenDefault opens the vault stored in the pass dir
+func OpenDefault() (*vault.Vault, error) {
+	d, err := io.GetPassDir()
+	if err != nil {
+		return nil, fmt.Errorf("could not get pass dir: %w", err)
+	}
+	return Open(Storage(d))
+}
+
+// Unlock asks for the master password and unlocks the vault with it,
+// unless it is unlocked already
+func Unlock(v *vault.Vault) error {
+	if !v.IsLocked() {
+		return nil
+	}
+	pass, err := io.PromptMasterPass("Please enter master password")
+	if err != nil {
+		return fmt.Errorf("could not read password: %w", err)
+	}
+	if err = v.Unlock(pass); err != nil {
+		return err
+	}
+	// keep the output of scripts to what they asked for
+	if !io.HasMasterPasswordSource() {
+		fmt.Println("Authentication success!")
+	}
+	return nil
+}
+
+// UnlockIndex unlocks the vault if its index is encrypted, so that its sites can be read
+func UnlockIndex(v *vault.Vault) error {
+	if !v.IndexEncrypted() {
+		return nil
+	}
+	return Unlock(v)
+}
@@ -0,0 +1,48 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/vault"
+)
+
+const testPassword = "master password"
+
+func TestOpenAndUnlock(t *testing.T) {
+	defer SetStorageOptions(vault.DefaultFileOptions())
+	SetStorageOptions(vault.FileOptions{LockTimeout: time.Second, AutoBackups: 2})
+	dir := filepath.Join(t.TempDir(), "mypass")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	s := Storage(dir)
+	if s.LockTimeout != time.Second || s.AutoBackups != 2 {
+		t.Fatalf("Storage() has options %+v", s.FileOptions)
+	}
+	if _, err := vault.Create(s, testPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte(testPassword+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	io.SetMasterPasswordFile(passwordFile)
+	defer io.SetMasterPasswordFile("")
+
+	v, err := Open(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a plaintext index is read without the master password
+	if err = UnlockIndex(v); err != nil || !v.IsLocked() {
+		t.Fatalf("UnlockIndex() of a plaintext index = %v, locked %v", err, v.IsLocked())
+	}
+	if err = Unlock(v); err != nil || v.IsLocked() {
+		t.Fatalf("Unlock() = %v, locked %v", err, v.IsLocked())
+	}
+}
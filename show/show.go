@@ -1,29 +1,44 @@
 package show
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"path/filepath"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/disiqueira/gotree"
+	"github.com/jeremyphua/mypass/clip"
+	"github.com/jeremyphua/mypass/find"
 	"github.com/jeremyphua/mypass/io"
-	"github.com/jeremyphua/mypass/pc"
+	"github.com/jeremyphua/mypass/session"
+	"github.com/jeremyphua/mypass/vault"
 )
 
 // list all sites
-func ListAll() {
-	allSites := GetSiteInfoByGroup()
+func ListAll() error {
+	allSites, err := GetSiteInfoByGroup()
+	if err != nil {
+		return err
+	}
 
 	showResults(allSites)
-
+	return nil
 }
 
-func GetSiteInfoByGroup() (allSites map[string]io.SiteFile) {
+func GetSiteInfoByGroup() (allSites map[string]io.SiteFile, err error) {
 	allSites = map[string]io.SiteFile{}
-	sf := getSiteFileContent()
+	v, err := session.OpenDefault()
+	if err != nil {
+		return nil, err
+	}
+	if err = session.UnlockIndex(v); err != nil {
+		return nil, err
+	}
+	sf, err := v.List()
+	if err != nil {
+		return nil, err
+	}
 	for _, s := range sf {
 		slashIndex := strings.LastIndex(s.Name, "/")
 		group := ""
@@ -53,63 +68,160 @@ func showResults(allSites map[string]io.SiteFile) {
 	fmt.Println(vault.Print())
 }
 
-// Site will print out the password of the site that matches path
-func Site(path string, copyPassword bool) {
-	// get site information from sites.json
-	// site, err := GetSiteInfo(path)
-	siteInfo := GetSiteInfo(path)
-	if (io.SiteInfo{}) == siteInfo {
-		log.Fatalf("Site with path %s not found", path)
+// Site will print out the password and details of the site that matches path,
+// or only the value of field when it is set, or a previous password when revision is above 0.
+// When copying, the clipboard is cleared after clearAfter unless it is 0.
+// The closest sites are offered when there is no such site only if prompt is set.
+func Site(path, field string, revision int, copyPassword bool, clearAfter time.Duration, prompt bool) error {
+	v, err := session.OpenDefault()
+	if err != nil {
+		return err
+	}
+
+	if err = session.UnlockIndex(v); err != nil {
+		return err
+	}
+
+	// get site information from sites.json, offering the closest sites if there is no such site
+	if path, err = find.Resolve(v, path, prompt); err != nil {
+		return err
 	}
 
 	// get master private key
-	masterPrivKey := pc.GetMasterPrivKey()
+	if err = session.Unlock(v); err != nil {
+		return err
+	}
+
+	switch {
+	case revision > 0:
+		err = showRevision(v, path, revision, copyPassword, clearAfter)
+	case field != "":
+		err = showField(v, path, field, copyPassword, clearAfter)
+	default:
+		// show password
+		err = showUsernameAndPassword(v, path, copyPassword, clearAfter)
+	}
+	if err != nil {
+		return err
+	}
 
-	// show password
-	showUsernameAndPassword(siteInfo, masterPrivKey, copyPassword)
+	// failing to record it does not take back what was shown, and is not worth a warning
+	// when another mypass is busy with the vault
+	if err = v.MarkShown(path); err != nil && !errors.Is(err, vault.ErrInUse) {
+		fmt.Fprintf(os.Stderr, "Warning: could not record that %s was shown: %s\n", path, err.Error())
+	}
+	return nil
 }
 
 // GetSiteInfo returns the site information for that particular entry
 // What we need from SiteInfo is the public key for the site
-func GetSiteInfo(searchFor string) (si io.SiteInfo) {
-	sf := getSiteFileContent()
-	for _, site := range sf {
-		if site.Name == searchFor {
-			return site
-		}
+func GetSiteInfo(searchFor string) (si io.SiteInfo, err error) {
+	v, err := session.OpenDefault()
+	if err != nil {
+		return
 	}
-	return
+	if err = session.UnlockIndex(v); err != nil {
+		return
+	}
+	return v.Site(searchFor)
 }
 
-func showUsernameAndPassword(siteInfo io.SiteInfo, masterPrivKey [32]byte, copyPassword bool) {
-	vault, err := io.GetVaultFolder()
+func showUsernameAndPassword(v *vault.Vault, path string, copyPassword bool, clearAfter time.Duration) error {
+	siteInfo, password, err := v.Get(path)
 	if err != nil {
-		log.Fatalf("Could not get vault: %s", err.Error())
-	}
-	encFilePath := filepath.Join(vault, siteInfo.Name)
-	encryptedPassword, err := ioutil.ReadFile(encFilePath)
-	password, ok := pc.BoxOpen(encryptedPassword, &siteInfo.PubKey, &masterPrivKey)
-	if !ok {
-		log.Fatalf("Error decryption password")
+		return err
 	}
 	fmt.Printf("Username: %-20s\n", siteInfo.Username)
 
-	if copyPassword {
-		io.ToClipboard(string(password))
-	} else {
+	if !copyPassword {
 		fmt.Printf("Password: %-20s\n", password)
 	}
+	if siteInfo.URL != "" {
+		fmt.Printf("URL: %s\n", siteInfo.URL)
+	}
+	fmt.Printf("Created: %s\n", formatTime(siteInfo.Created, "unknown"))
+	fmt.Printf("Password changed: %s\n", formatTime(siteInfo.PasswordChanged, "unknown"))
+	fmt.Printf("Last shown: %s\n", formatTime(siteInfo.LastShown, "never"))
+	if copyPassword {
+		// the point of copying is to keep secrets off the screen, skip the secret fields and notes
+		for _, f := range siteInfo.Fields {
+			if !f.IsSecret() {
+				fmt.Printf("%s: %s\n", f.Name, f.Value)
+			}
+		}
+		return clip.Copy(clip.SystemClipboard, "Password", password, clearAfter)
+	}
+	for _, f := range siteInfo.Fields {
+		value, err := v.FieldValue(f)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s\n", f.Name, value)
+	}
+	notes, err := v.Notes(siteInfo)
+	if err != nil {
+		return err
+	}
+	if notes != "" {
+		fmt.Printf("Notes:\n%s\n", notes)
+	}
+	return nil
 }
 
-func getSiteFileContent() (sf io.SiteFile) {
-	siteFile, err := io.GetSiteFile()
+// Print or copy a single value of a site, either a built-in one or a custom field
+func showField(v *vault.Vault, path, field string, copyValue bool, clearAfter time.Duration) error {
+	siteInfo, password, err := v.Get(path)
 	if err != nil {
-		log.Fatalf("Could not get site file: %s", err.Error())
+		return err
 	}
-	fileBytes, err := ioutil.ReadFile(siteFile)
+	var name, value string
+	switch strings.ToLower(field) {
+	case "username":
+		name, value = "Username", siteInfo.Username
+	case "password":
+		name, value = "Password", password
+	case "url":
+		name, value = "URL", siteInfo.URL
+	case "notes":
+		name = "Notes"
+		if value, err = v.Notes(siteInfo); err != nil {
+			return err
+		}
+	default:
+		index, err := siteInfo.Field(field)
+		if err != nil {
+			return err
+		}
+		name = siteInfo.Fields[index].Name
+		if value, err = v.FieldValue(siteInfo.Fields[index]); err != nil {
+			return err
+		}
+	}
+	if copyValue {
+		return clip.Copy(clip.SystemClipboard, name, value, clearAfter)
+	}
+	fmt.Println(value)
+	return nil
+}
+
+// Print or copy a previous password of a site
+func showRevision(v *vault.Vault, path string, revision int, copyPassword bool, clearAfter time.Duration) error {
+	rev, password, err := v.Revision(path, revision)
 	if err != nil {
-		log.Fatalf("Could not read site file: %s", err.Error())
+		return err
 	}
-	json.Unmarshal(fileBytes, &sf)
-	return
+	fmt.Printf("Revision %d, replaced on %s\n", revision, formatTime(rev.Replaced, "unknown"))
+	if copyPassword {
+		return clip.Copy(clip.SystemClipboard, "Password", password, clearAfter)
+	}
+	fmt.Printf("Password: %-20s\n", password)
+	return nil
+}
+
+// Format a timestamp of a site in local time, or return unset when it was never recorded
+func formatTime(t time.Time, unset string) string {
+	if t.IsZero() {
+		return unset
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
 }
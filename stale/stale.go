@@ -0,0 +1,60 @@
+package stale
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/session"
+)
+
+// ErrStaleFound is returned when at least one site is due for a new password
+var ErrStaleFound = errors.New("sites with stale passwords found")
+
+// List prints the sites whose password was last changed more than olderThan ago, oldest first.
+// Sites added before the change time was recorded are listed as unknown.
+func List(olderThan time.Duration) error {
+	if err := io.RequireVault(); err != nil {
+		return err
+	}
+
+	v, err := session.OpenDefault()
+	if err != nil {
+		return err
+	}
+	if err = session.UnlockIndex(v); err != nil {
+		return err
+	}
+	sites, err := v.List()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var stale io.SiteFile
+	for _, si := range sites {
+		if si.PasswordChanged.IsZero() || now.Sub(si.PasswordChanged) > olderThan {
+			stale = append(stale, si)
+		}
+	}
+	if len(stale) == 0 {
+		fmt.Println("No stale passwords found")
+		return nil
+	}
+
+	// unknown change times sort first
+	sort.SliceStable(stale, func(i, j int) bool {
+		return stale[i].PasswordChanged.Before(stale[j].PasswordChanged)
+	})
+	for _, si := range stale {
+		if si.PasswordChanged.IsZero() {
+			fmt.Printf("%-30s changed at an unknown time\n", si.Name)
+			continue
+		}
+		days := int(now.Sub(si.PasswordChanged).Hours() / 24)
+		fmt.Printf("%-30s changed %s, %d days ago\n", si.Name, si.PasswordChanged.Local().Format("2006-01-02"), days)
+	}
+	return fmt.Errorf("%w: %d of %d sites", ErrStaleFound, len(stale), len(sites))
+}
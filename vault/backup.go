@@ -0,0 +1,97 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jeremyphua/mypass/io"
+)
+
+// BackupFolderName is the folder of the pass dir holding the backups of the vault
+const BackupFolderName = "backups"
+
+// Backup copies masterpass, sites.json and the vault folder to backups/<label> in the pass dir
+func (f *FileStorage) Backup(label string) (string, error) {
+	dest := filepath.Join(f.dir, BackupFolderName, label)
+	if err := os.MkdirAll(dest, 0700); err != nil {
+		return "", fmt.Errorf("could not create backup folder: %w", err)
+	}
+	for _, name := range []string{io.ConfigFileName, io.SiteFileName} {
+		if err := copyFile(filepath.Join(f.dir, name), filepath.Join(dest, name)); err != nil {
+			return "", err
+		}
+	}
+	vault := f.vaultFolder()
+	err := filepath.Walk(vault, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(f.dir, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dest, rel), 0700)
+		}
+		return copyFile(path, filepath.Join(dest, rel))
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not back up the vault folder: %w", err)
+	}
+	return dest, nil
+}
+
+// BackupRemover is implemented by storages keeping backups next to the vault. The vault removes
+// them once they would undo an operation, like holding the keys replaced by RotateKeys.
+type BackupRemover interface {
+	RemoveBackups() ([]string, error)
+}
+
+// RemoveBackups deletes the backup folders and rolling backups in the pass dir and returns their paths
+func (f *FileStorage) RemoveBackups() ([]string, error) {
+	dir := filepath.Join(f.dir, BackupFolderName)
+	var paths []string
+	for _, folder := range []string{dir, filepath.Join(dir, AutoBackupFolderName)} {
+		files, err := os.ReadDir(folder)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("could not list backups: %w", err)
+		}
+		for _, file := range files {
+			if folder == dir && file.Name() == AutoBackupFolderName {
+				continue
+			}
+			paths = append(paths, filepath.Join(folder, file.Name()))
+		}
+	}
+	for i, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			return paths[:i], fmt.Errorf("could not remove backup: %w", err)
+		}
+	}
+	return paths, nil
+}
+
+// Remove the backups of the storage, if it keeps any, after an operation they would undo
+func (v *Vault) removeBackups(reason string) ([]string, error) {
+	r, ok := v.storage.(BackupRemover)
+	if !ok {
+		return nil, nil
+	}
+	removed, err := r.RemoveBackups()
+	if err != nil {
+		return removed, fmt.Errorf("could not remove the backups %s: %w", reason, err)
+	}
+	return removed, nil
+}
+
+func copyFile(from, to string) error {
+	data, err := os.ReadFile(from)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", from, err)
+	}
+	if err = io.WriteFileAtomic(to, data, 0600); err != nil {
+		return fmt.Errorf("could not write %s: %w", to, err)
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/pc"
+)
+
+// ProblemKind classifies the problems found by Check
+type ProblemKind string
+
+// Problems found by Check
+const (
+	// a site name in sites.json is not a valid site name, the site can still be renamed
+	ProblemInvalidName ProblemKind = "invalid name"
+	// several sites in sites.json have the same name
+	ProblemDuplicate ProblemKind = "duplicate"
+	// a site in sites.json has no password file
+	ProblemDangling ProblemKind = "dangling"
+	// a password file belongs to no site in sites.json
+	ProblemOrphan ProblemKind = "orphan"
+	// a folder of the vault folder holds no password file
+	ProblemEmptyFolder ProblemKind = "empty folder"
+	// a password or previous password does not decrypt with the master key
+	ProblemUndecryptable ProblemKind = "undecryptable"
+)
+
+// Problem is an inconsistency between sites.json and the stored passwords
+type Problem struct {
+	Kind ProblemKind
+	// Site is the name of the site in sites.json, empty for files no site points to
+	Site string
+	// Entry is the name of the stored password file or folder, opaque in an encrypted index
+	Entry string
+	// Detail explains the problem
+	Detail string
+	// Repairable tells whether Repair fixes the problem
+	Repairable bool
+}
+
+func (p Problem) String() string {
+	name := p.Site
+	if name == "" {
+		name = p.Entry
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Kind, name, p.Detail)
+}
+
+// FolderPruner is implemented by storages keeping sites in nested folders,
+// which deleting and renaming sites can leave empty
+type FolderPruner interface {
+	// EmptyFolders returns the topmost folders holding no password file
+	EmptyFolders() ([]string, error)
+	// RemoveFolder removes a folder holding no password file
+	RemoveFolder(name string) error
+}
+
+// Check compares sites.json with the stored passwords. Duplicate names, sites without a
+// password file, password files without a site and empty folders are reported.
+// When the vault is unlocked every password and previous password is also decrypted.
+func (v *Vault) Check() ([]Problem, error) {
+	if v.config.EncryptedIndex && v.IsLocked() {
+		return nil, ErrLocked
+	}
+	sites, err := v.readSites()
+	if err != nil {
+		return nil, err
+	}
+	stored, err := v.storage.ListEntries()
+	if err != nil {
+		return nil, fmt.Errorf("could not list the password files: %w", err)
+	}
+	entries := map[string]bool{}
+	for _, name := range stored {
+		entries[name] = true
+	}
+
+	var problems []Problem
+	seen := map[string]bool{}
+	referenced := map[string]bool{}
+	for index, si := range sites {
+		if _, err := io.NormalizeSiteName(si.Name); err != nil {
+			problems = append(problems, Problem{Kind: ProblemInvalidName, Site: si.Name, Detail: renameAdvice(si.Name, err)})
+			// names stored before they were validated still have a password file to check
+			if io.CheckStoredSiteName(si.Name) != nil {
+				continue
+			}
+		}
+		if seen[si.Name] {
+			// only the password of the site written last opens the shared password file,
+			// which cannot be told apart without decrypting
+			problems = append(problems, Problem{Kind: ProblemDuplicate, Site: si.Name,
+				Detail: fmt.Sprintf("site %d of sites.json has the name of an earlier site", index+1), Repairable: !v.IsLocked()})
+			continue
+		}
+		seen[si.Name] = true
+		entry := entryName(si.Name, v.config.EncryptedIndex, v.masterPrivKey)
+		referenced[entry] = true
+		if !entries[entry] {
+			problems = append(problems, Problem{Kind: ProblemDangling, Site: si.Name, Entry: entry,
+				Detail: "its password file is missing", Repairable: true})
+			continue
+		}
+		if !v.IsLocked() {
+			problems = append(problems, v.checkDecrypt(si, entry)...)
+		}
+	}
+
+	for _, name := range stored {
+		if referenced[name] {
+			continue
+		}
+		problems = append(problems, Problem{Kind: ProblemOrphan, Entry: name,
+			Detail: "no site in sites.json points to this password file", Repairable: true})
+	}
+
+	if p, ok := v.storage.(FolderPruner); ok {
+		folders, err := p.EmptyFolders()
+		if err != nil {
+			return nil, err
+		}
+		for _, folder := range folders {
+			problems = append(problems, Problem{Kind: ProblemEmptyFolder, Entry: folder,
+				Detail: "the folder holds no password file", Repairable: true})
+		}
+	}
+	return problems, nil
+}
+
+// Explain why a stored site name is invalid and how to rename it
+func renameAdvice(name string, err error) string {
+	suggestion := io.SuggestSiteName(name)
+	if suggestion == "" {
+		suggestion = "<new name>"
+	}
+	return fmt.Sprintf("%s, rename it with mypass rename '%s' %s", err.Error(), strings.ReplaceAll(name, "'", `'\''`), suggestion)
+}
+
+// Decrypt the password and previous passwords of a site
+func (v *Vault) checkDecrypt(si io.SiteInfo, entry string) []Problem {
+	var problems []Problem
+	data, err := v.storage.ReadEntry(entry)
+	if err == nil {
+		data, err = openEntryData(data)
+	}
+	if err != nil {
+		problems = append(problems, Problem{Kind: ProblemUndecryptable, Site: si.Name, Entry: entry, Detail: err.Error()})
+	} else if _, ok := pc.BoxOpen(data, &si.PubKey, v.masterPrivKey); !ok {
+		problems = append(problems, Problem{Kind: ProblemUndecryptable, Site: si.Name, Entry: entry,
+			Detail: "the password does not decrypt with the key of the site"})
+	}
+	for i, rev := range si.History {
+		if _, ok := pc.BoxOpen(rev.Sealed, &rev.PubKey, v.masterPrivKey); !ok {
+			problems = append(problems, Problem{Kind: ProblemUndecryptable, Site: si.Name, Entry: entry,
+				Detail: fmt.Sprintf("revision %d does not decrypt", i+1)})
+		}
+	}
+	return problems
+}
+
+// Repair fixes the repairable problems found by Check, after a rolling backup of the vault.
+// Duplicates keep the first site whose password decrypts, or the first site, and are only
+// repaired on an unlocked vault. Sites without a password file are removed from sites.json,
+// password files without a site and empty folders are removed. Problems that are not
+// repairable are skipped. It returns the number of sites, files and folders removed.
+func (v *Vault) Repair(problems []Problem) (int, error) {
+	var orphans []string
+	dangling, duplicates := map[string]bool{}, map[string]bool{}
+	for _, p := range problems {
+		if !p.Repairable {
+			continue
+		}
+		switch p.Kind {
+		case ProblemOrphan:
+			orphans = append(orphans, p.Entry)
+		case ProblemDangling:
+			dangling[p.Site] = true
+		case ProblemDuplicate:
+			if v.IsLocked() {
+				return 0, fmt.Errorf("%w: duplicate site %s is only repaired once the vault is unlocked", ErrLocked, p.Site)
+			}
+			duplicates[p.Site] = true
+		}
+	}
+	fixed := 0
+	if len(orphans) > 0 || len(dangling) > 0 || len(duplicates) > 0 {
+		err := v.atomic(orphans, func() error {
+			if err := v.autoBackup("fsck"); err != nil {
+				return err
+			}
+			sites, err := v.readSites()
+			if err != nil {
+				return err
+			}
+			keep := v.keptDuplicates(sites, duplicates)
+			var repaired io.SiteFile
+			for index, si := range sites {
+				switch {
+				case dangling[si.Name], duplicates[si.Name] && keep[si.Name] != index:
+					fixed++
+				default:
+					repaired = append(repaired, si)
+				}
+			}
+			for _, name := range orphans {
+				if err = v.storage.RemoveEntry(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("could not remove password file %s: %w", name, err)
+				}
+				fixed++
+			}
+			return v.writeSites(repaired)
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if p, ok := v.storage.(FolderPruner); ok {
+		// removing orphans may have emptied more folders
+		empty, err := p.EmptyFolders()
+		if err != nil {
+			return fixed, err
+		}
+		for _, folder := range empty {
+			if err = p.RemoveFolder(folder); err != nil {
+				return fixed, fmt.Errorf("could not remove folder %s: %w", folder, err)
+			}
+			fixed++
+		}
+	}
+	return fixed, nil
+}
+
+// Index of the site kept for every duplicated name: the first whose password decrypts, else the first
+func (v *Vault) keptDuplicates(sites io.SiteFile, duplicates map[string]bool) map[string]int {
+	keep := map[string]int{}
+	decrypts := map[string]bool{}
+	for index, si := range sites {
+		if !duplicates[si.Name] || decrypts[si.Name] {
+			continue
+		}
+		if _, ok := keep[si.Name]; !ok {
+			keep[si.Name] = index
+		}
+		if data, err := v.readEntry(si.Name); err == nil {
+			if _, ok := pc.BoxOpen(data, &si.PubKey, v.masterPrivKey); ok {
+				keep[si.Name] = index
+				decrypts[si.Name] = true
+			}
+		}
+	}
+	return keep
+}
+
+// EmptyFolders returns the topmost folders of the vault folder holding no password file
+func (f *FileStorage) EmptyFolders() ([]string, error) {
+	vault := f.vaultFolder()
+	var empty []string
+	var walk func(dir string) (bool, error)
+	// walk tells whether dir holds a file, collecting its topmost empty subfolders
+	walk = func(dir string) (bool, error) {
+		items, err := os.ReadDir(dir)
+		if err != nil {
+			return false, err
+		}
+		hasFile := false
+		var emptySubs []string
+		for _, item := range items {
+			path := filepath.Join(dir, item.Name())
+			if !item.IsDir() {
+				hasFile = true
+				continue
+			}
+			sub, err := walk(path)
+			if err != nil {
+				return false, err
+			}
+			if sub {
+				hasFile = true
+			} else {
+				emptySubs = append(emptySubs, path)
+			}
+		}
+		// an empty folder is reported rather than each of its empty subfolders
+		if hasFile || dir == vault {
+			for _, sub := range emptySubs {
+				rel, err := filepath.Rel(vault, sub)
+				if err != nil {
+					return false, err
+				}
+				empty = append(empty, filepath.ToSlash(rel))
+			}
+		}
+		return hasFile, nil
+	}
+	if _, err := walk(vault); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not list the vault folder: %w", err)
+	}
+	sort.Strings(empty)
+	return empty, nil
+}
+
+// RemoveFolder removes a folder of the vault folder and its subfolders, failing if any holds a file
+func (f *FileStorage) RemoveFolder(name string) error {
+	if err := io.CheckStoredSiteName(name); err != nil {
+		return err
+	}
+	root := filepath.Join(f.vaultFolder(), filepath.FromSlash(name))
+	var dirs []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not empty", name)
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// deepest first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err = os.Remove(dirs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package vault
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// Break a file vault in every way Check reports
+func newBrokenVault(t *testing.T) (*Vault, string) {
+	t.Helper()
+	dir := newFileVault(t)
+	s := NewFileStorage(dir, DefaultFileOptions())
+	v := openUnlocked(t, s, testPassword)
+	for _, name := range []string{"bank", "other"} {
+		if err := v.Put(site(name), "pw"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mail, err := s.ReadEntry("work/mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sites, err := v.readSites()
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := sites[0]
+	invalid.Name = "my bank"
+	if err = v.writeSites(append(sites, sites[0], invalid)); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		// sealed for the key of work/mail
+		"my bank": mail,
+		"other":   mail,
+		// no site points to it
+		"lost/stray": mail,
+	} {
+		if err = s.WriteEntry(name, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = os.Remove(entryPath(dir, "bank")); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(entryPath(dir, "old/empty"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return v, dir
+}
+
+// Kinds of the problems found, by site or entry name
+func problemKinds(problems []Problem) map[string][]ProblemKind {
+	kinds := map[string][]ProblemKind{}
+	for _, p := range problems {
+		name := p.Site
+		if name == "" {
+			name = p.Entry
+		}
+		kinds[name] = append(kinds[name], p.Kind)
+	}
+	for _, k := range kinds {
+		sort.Slice(k, func(i, j int) bool { return k[i] < k[j] })
+	}
+	return kinds
+}
+
+func TestCheck(t *testing.T) {
+	v, _ := newBrokenVault(t)
+	problems, err := v.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]ProblemKind{
+		"my bank":    {ProblemInvalidName},
+		"work/mail":  {ProblemDuplicate},
+		"bank":       {ProblemDangling},
+		"other":      {ProblemUndecryptable},
+		"lost/stray": {ProblemOrphan},
+		"old":        {ProblemEmptyFolder},
+	}
+	if got := problemKinds(problems); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Check() found %v, want %v", got, want)
+	}
+	for _, p := range problems {
+		repairable := p.Kind != ProblemInvalidName && p.Kind != ProblemUndecryptable
+		if p.Repairable != repairable {
+			t.Errorf("%s: Repairable = %v, want %v", p, p.Repairable, repairable)
+		}
+	}
+}
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		name   string
+		locked bool
+		// number of problems fixed
+		fixed int
+		// problems left
+		want map[string][]ProblemKind
+	}{
+		// the dangling site, the duplicate, the orphan and the empty folder
+		{"unlocked", false, 4, map[string][]ProblemKind{"my bank": {ProblemInvalidName}, "other": {ProblemUndecryptable}}},
+		// the duplicate is kept until the vault is unlocked
+		{"locked", true, 3, map[string][]ProblemKind{"my bank": {ProblemInvalidName}, "work/mail": {ProblemDuplicate}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, dir := newBrokenVault(t)
+			if tt.locked {
+				v = openLocked(t, NewFileStorage(dir, DefaultFileOptions()))
+			}
+			problems, err := v.Check()
+			if err != nil {
+				t.Fatal(err)
+			}
+			fixed, err := v.Repair(problems)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fixed != tt.fixed {
+				t.Errorf("Repair() fixed %d problems, want %d", fixed, tt.fixed)
+			}
+			if problems, err = v.Check(); err != nil {
+				t.Fatal(err)
+			}
+			if got := problemKinds(problems); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Check() after Repair() found %v, want %v", got, tt.want)
+			}
+			if err = v.Unlock(testPassword); err != nil {
+				t.Fatal(err)
+			}
+			if _, password, err := v.Get("work/mail"); err != nil || password != "secret" {
+				t.Errorf("Get(work/mail) = %q, %v after Repair()", password, err)
+			}
+			for _, name := range []string{"lost", "old"} {
+				if _, err = os.Stat(entryPath(dir, name)); !os.IsNotExist(err) {
+					t.Errorf("%s still exists: %v", name, err)
+				}
+			}
+			if backups, _ := filepath.Glob(filepath.Join(dir, BackupFolderName, AutoBackupFolderName, "*")); len(backups) != 1 {
+				t.Errorf("backups after the repair: %v, want the one taken before it", backups)
+			}
+		})
+	}
+}
+
+// A locked vault cannot tell which duplicate opens the password file
+func TestRepairDuplicateOfChangedSite(t *testing.T) {
+	v, s := newTestVault(t)
+	if err := v.Add(site("a"), "old"); err != nil {
+		t.Fatal(err)
+	}
+	sites, err := v.readSites()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the new key of the site is written after the old one
+	if err = v.Put(site("a"), "new"); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := v.readSites()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = v.writeSites(append(sites, changed...)); err != nil {
+		t.Fatal(err)
+	}
+
+	locked := openLocked(t, s)
+	problems, err := locked.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0].Kind != ProblemDuplicate || problems[0].Repairable {
+		t.Fatalf("Check() on a locked vault = %v, want a duplicate that is not repairable", problems)
+	}
+	if fixed, err := locked.Repair(problems); err != nil || fixed != 0 {
+		t.Fatalf("Repair() on a locked vault = %d, %v", fixed, err)
+	}
+	problems[0].Repairable = true
+	if _, err = locked.Repair(problems); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Repair() of a duplicate on a locked vault = %v, want ErrLocked", err)
+	}
+
+	if problems, err = v.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if fixed, err := v.Repair(problems); err != nil || fixed != 1 {
+		t.Fatalf("Repair() = %d, %v", fixed, err)
+	}
+	if _, password, err := v.Get("a"); err != nil || password != "new" {
+		t.Errorf("Get(a) = %q, %v after Repair(), want new", password, err)
+	}
+}
+
+func TestCheckLockedEncryptedIndex(t *testing.T) {
+	v, s := newTestVault(t)
+	if _, err := v.EncryptIndex(true, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openLocked(t, s).Check(); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Check() = %v, want ErrLocked", err)
+	}
+	// a locked vault with a plain index is checked without decrypting
+	v, s = newTestVault(t)
+	if err := v.Add(site("bank"), "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if problems, err := openLocked(t, s).Check(); err != nil || len(problems) > 0 {
+		t.Fatalf("Check() = %v, %v", problems, err)
+	}
+}
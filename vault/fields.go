@@ -0,0 +1,138 @@
+package vault
+
+import (
+	"fmt"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/pc"
+)
+
+// FieldInput is the plaintext of a custom field to store on a site
+type FieldInput struct {
+	Name   string
+	Value  string
+	Secret bool
+}
+
+// Details are the optional parts of a site changed by add and edit.
+// A nil URL or Notes keeps the current value, an empty one clears it.
+type Details struct {
+	URL    *string
+	Notes  *string
+	Fields []FieldInput
+	Remove []string
+}
+
+// IsEmpty tells whether d changes nothing
+func (d Details) IsEmpty() bool {
+	return d.URL == nil && d.Notes == nil && len(d.Fields) == 0 && len(d.Remove) == 0
+}
+
+// ApplyDetails sets the details of d on site, sealing notes and secret fields
+// to the master public key. It does not need the vault to be unlocked.
+func (v *Vault) ApplyDetails(site *io.SiteInfo, d Details) error {
+	if d.URL != nil {
+		site.URL = *d.URL
+	}
+	if d.Notes != nil {
+		site.Notes = nil
+		if *d.Notes != "" {
+			sealed, err := pc.SealAnonymous([]byte(*d.Notes), &v.config.MasterPubKey)
+			if err != nil {
+				return fmt.Errorf("could not seal notes of %s: %w", site.Name, err)
+			}
+			site.Notes = sealed
+		}
+	}
+	for _, name := range d.Remove {
+		if err := site.RemoveField(name); err != nil {
+			return err
+		}
+	}
+	for _, in := range d.Fields {
+		name, err := io.NormalizeFieldName(in.Name)
+		if err != nil {
+			return err
+		}
+		f := io.Field{Name: name}
+		if !in.Secret {
+			f.Value = in.Value
+		} else if f.Sealed, err = pc.SealAnonymous([]byte(in.Value), &v.config.MasterPubKey); err != nil {
+			return fmt.Errorf("could not seal field %s of %s: %w", name, site.Name, err)
+		}
+		site.SetField(f)
+	}
+	return nil
+}
+
+// FieldValue returns the value of a custom field, opening it if it is secret
+func (v *Vault) FieldValue(f io.Field) (string, error) {
+	if !f.IsSecret() {
+		return f.Value, nil
+	}
+	value, err := v.open(f.Sealed)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting field %s: %w", f.Name, err)
+	}
+	return value, nil
+}
+
+// Notes returns the opened notes of a site
+func (v *Vault) Notes(site io.SiteInfo) (string, error) {
+	if site.Notes == nil {
+		return "", nil
+	}
+	notes, err := v.open(site.Notes)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting notes of %s: %w", site.Name, err)
+	}
+	return notes, nil
+}
+
+// Open a value sealed to the master public key
+func (v *Vault) open(sealed []byte) (string, error) {
+	if v.IsLocked() {
+		return "", ErrLocked
+	}
+	value, ok := pc.OpenAnonymous(sealed, &v.config.MasterPubKey, v.masterPrivKey)
+	if !ok {
+		return "", fmt.Errorf("could not open sealed value")
+	}
+	return string(value), nil
+}
+
+// Re-seal the notes, secret fields and previous passwords of site to a new master public key
+func (v *Vault) resealDetails(site io.SiteInfo, masterPub *[32]byte) (io.SiteInfo, error) {
+	history, err := v.resealHistory(site.History, masterPub)
+	if err != nil {
+		return site, err
+	}
+	site.History = history
+	if site.Notes != nil {
+		notes, err := v.Notes(site)
+		if err != nil {
+			return site, err
+		}
+		if site.Notes, err = pc.SealAnonymous([]byte(notes), masterPub); err != nil {
+			return site, err
+		}
+	}
+	// copy the fields, site shares them with the index that is being replaced
+	fields := make([]io.Field, len(site.Fields))
+	for i, f := range site.Fields {
+		if f.IsSecret() {
+			value, err := v.FieldValue(f)
+			if err != nil {
+				return site, err
+			}
+			if f.Sealed, err = pc.SealAnonymous([]byte(value), masterPub); err != nil {
+				return site, err
+			}
+		}
+		fields[i] = f
+	}
+	if site.Fields != nil {
+		site.Fields = fields
+	}
+	return site, nil
+}
@@ -0,0 +1,106 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/jeremyphua/mypass/io"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestApplyDetails(t *testing.T) {
+	v, s := newTestVault(t)
+	// details are sealed to the master public key, without unlocking
+	locked := openLocked(t, s)
+	si := site("bank")
+	err := locked.ApplyDetails(&si, Details{
+		URL:   strPtr("https://bank.com"),
+		Notes: strPtr("joint account"),
+		Fields: []FieldInput{
+			{Name: "branch", Value: "Orchard"},
+			{Name: " pin ", Value: "1234", Secret: true},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = locked.Add(si, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	index, err := s.ReadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"joint account", "1234"} {
+		if bytes.Contains(index, []byte(secret)) {
+			t.Errorf("sites.json holds %q in plain text", secret)
+		}
+	}
+
+	if si, err = v.Site("bank"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = locked.Notes(si); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Notes() on a locked vault = %v, want ErrLocked", err)
+	}
+	checkDetails(t, v, si, "https://bank.com", "joint account", map[string]string{"branch": "Orchard", "pin": "1234"})
+
+	// nil values are kept, empty ones are cleared
+	err = v.ApplyDetails(&si, Details{Notes: strPtr(""), Fields: []FieldInput{{Name: "pin", Value: "0000"}}, Remove: []string{"branch"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if si.Notes != nil || si.Fields[0].IsSecret() {
+		t.Fatalf("notes %v and pin %+v were not replaced", si.Notes, si.Fields[0])
+	}
+	checkDetails(t, v, si, "https://bank.com", "", map[string]string{"pin": "0000"})
+
+	if err = v.ApplyDetails(&si, Details{Fields: []FieldInput{{Name: "password", Value: "x"}}}); !errors.Is(err, io.ErrInvalidFieldName) {
+		t.Fatalf("ApplyDetails() of a reserved field = %v, want ErrInvalidFieldName", err)
+	}
+	if err = v.ApplyDetails(&si, Details{Remove: []string{"missing"}}); !errors.Is(err, io.ErrFieldNotFound) {
+		t.Fatalf("ApplyDetails() removing a missing field = %v, want ErrFieldNotFound", err)
+	}
+}
+
+func TestRotateKeysResealsDetails(t *testing.T) {
+	v, _ := newTestVault(t)
+	si := site("bank")
+	d := Details{Notes: strPtr("joint account"), Fields: []FieldInput{{Name: "pin", Value: "1234", Secret: true}}}
+	if err := v.ApplyDetails(&si, d); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Add(si, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := v.RotateKeys(false); err != nil {
+		t.Fatal(err)
+	}
+	si, err := v.Site("bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDetails(t, v, si, "", "joint account", map[string]string{"pin": "1234"})
+}
+
+func checkDetails(t *testing.T, v *Vault, si io.SiteInfo, url, notes string, fields map[string]string) {
+	t.Helper()
+	if si.URL != url {
+		t.Errorf("URL = %q, want %q", si.URL, url)
+	}
+	if got, err := v.Notes(si); err != nil || got != notes {
+		t.Errorf("Notes() = %q, %v, want %q", got, err, notes)
+	}
+	if len(si.Fields) != len(fields) {
+		t.Errorf("fields are %+v, want %v", si.Fields, fields)
+	}
+	for _, f := range si.Fields {
+		if got, err := v.FieldValue(f); err != nil || got != fields[f.Name] {
+			t.Errorf("FieldValue(%s) = %q, %v, want %q", f.Name, got, err, fields[f.Name])
+		}
+	}
+}
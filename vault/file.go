@@ -0,0 +1,306 @@
+package vault
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jeremyphua/mypass/io"
+)
+
+const (
+	// suffix of the files and folders staged by Replace
+	stagedSuffix = ".staged"
+	// suffix of the previous files and folders kept until Replace succeeded
+	backupSuffix = ".old"
+)
+
+// FileStorage stores a vault in a directory using the mypass layout:
+// masterpass, sites.json and the vault folder holding one file per site
+type FileStorage struct {
+	dir string
+	FileOptions
+
+	mu      sync.Mutex
+	lock    *os.File
+	holders int
+}
+
+// FileOptions tune how a FileStorage shares and protects the pass dir
+type FileOptions struct {
+	// LockTimeout is how long Acquire waits for another process to release the vault
+	LockTimeout time.Duration
+	// FixPermissions makes CheckPermissions repair insecure modes instead of failing
+	FixPermissions bool
+	// AutoBackups is how many rolling backups AutoBackup keeps, 0 turns them off
+	AutoBackups int
+}
+
+// DefaultFileOptions wait 10 seconds for other processes, refuse insecure modes and keep 10 rolling backups
+func DefaultFileOptions() FileOptions {
+	return FileOptions{LockTimeout: 10 * time.Second, AutoBackups: 10}
+}
+
+// NewFileStorage returns a FileStorage for the pass dir d
+func NewFileStorage(d string, opts FileOptions) *FileStorage {
+	return &FileStorage{dir: d, FileOptions: opts}
+}
+
+// Dir returns the pass dir of the storage
+func (f *FileStorage) Dir() string {
+	return f.dir
+}
+
+func (f *FileStorage) configFile() string {
+	return filepath.Join(f.dir, io.ConfigFileName)
+}
+
+func (f *FileStorage) siteFile() string {
+	return filepath.Join(f.dir, io.SiteFileName)
+}
+
+func (f *FileStorage) vaultFolder() string {
+	return filepath.Join(f.dir, io.VaultFolderName)
+}
+
+// Init creates the vault folder, which a vault without sites needs as well
+func (f *FileStorage) Init() error {
+	if err := os.MkdirAll(f.vaultFolder(), 0700); err != nil {
+		return fmt.Errorf("could not create vault folder: %w", err)
+	}
+	return nil
+}
+
+// Path of the sealed password of a site. Names that could point outside
+// the vault folder are refused even if they made it into sites.json.
+func (f *FileStorage) entryFile(name string) (string, error) {
+	if err := io.CheckStoredSiteName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(f.vaultFolder(), filepath.FromSlash(name)), nil
+}
+
+func (f *FileStorage) ReadConfig() ([]byte, error) {
+	return ioutil.ReadFile(f.configFile())
+}
+
+// losing masterpass halfway through a write would make the whole vault unreadable
+func (f *FileStorage) WriteConfig(data []byte) error {
+	return io.WriteFileAtomic(f.configFile(), data, 0600)
+}
+
+func (f *FileStorage) ReadIndex() ([]byte, error) {
+	return ioutil.ReadFile(f.siteFile())
+}
+
+func (f *FileStorage) WriteIndex(data []byte) error {
+	return io.WriteFileAtomic(f.siteFile(), data, 0600)
+}
+
+func (f *FileStorage) ReadEntry(name string) ([]byte, error) {
+	encFilePath, err := f.entryFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(encFilePath)
+}
+
+func (f *FileStorage) WriteEntry(name string, data []byte) error {
+	encFilePath, err := f.entryFile(name)
+	if err != nil {
+		return err
+	}
+	// Make sure that the group directory exists.
+	if err := os.MkdirAll(filepath.Dir(encFilePath), 0700); err != nil {
+		return fmt.Errorf("could not create subdirectory: %w", err)
+	}
+	return io.WriteFileAtomic(encFilePath, data, 0600)
+}
+
+func (f *FileStorage) RemoveEntry(name string) error {
+	encFilePath, err := f.entryFile(name)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(encFilePath); err != nil {
+		return err
+	}
+	f.pruneFolders(encFilePath)
+	return nil
+}
+
+func (f *FileStorage) RenameEntry(oldName, newName string) error {
+	oldFilePath, err := f.entryFile(oldName)
+	if err != nil {
+		return err
+	}
+	newFilePath, err := f.entryFile(newName)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(newFilePath), 0700); err != nil {
+		return fmt.Errorf("could not create subdirectory: %w", err)
+	}
+	if err = os.Rename(oldFilePath, newFilePath); err != nil {
+		return err
+	}
+	f.pruneFolders(oldFilePath)
+	return nil
+}
+
+// Remove the group folders left empty above a removed entry, up to the vault folder.
+// A rollback of the journal creates them again.
+func (f *FileStorage) pruneFolders(entryPath string) {
+	for dir := filepath.Dir(entryPath); dir != f.vaultFolder(); dir = filepath.Dir(dir) {
+		// fails once a folder still holds other sites
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+func (f *FileStorage) ListEntries() (names []string, err error) {
+	vault := f.vaultFolder()
+	// vaults restored without sites by an earlier mypass have no vault folder
+	if _, err = os.Stat(vault); os.IsNotExist(err) {
+		return nil, nil
+	}
+	err = filepath.Walk(vault, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(vault, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	return
+}
+
+// Replace stages the new vault folder, sites.json and masterpass next to the current ones
+// and swaps them in together. The previous ones are restored if any rename fails.
+func (f *FileStorage) Replace(config, index []byte, entries map[string][]byte) error {
+	stagedVault := f.vaultFolder() + stagedSuffix
+	stagedSiteFile := f.siteFile() + stagedSuffix
+	stagedConfigFile := f.configFile() + stagedSuffix
+	staged := []string{stagedVault, stagedSiteFile, stagedConfigFile}
+
+	if err := f.recoverJournal(); err != nil {
+		return err
+	}
+	// leftovers of an interrupted replace are never swapped in, start from scratch
+	removeAll(staged...)
+	removeAll(f.vaultFolder()+backupSuffix, f.siteFile()+backupSuffix, f.configFile()+backupSuffix)
+	// vaults restored without sites by an earlier mypass have no vault folder to move aside
+	if err := f.Init(); err != nil {
+		return err
+	}
+	if err := stage(stagedVault, stagedSiteFile, stagedConfigFile, config, index, entries); err != nil {
+		removeAll(staged...)
+		return err
+	}
+	// a crash while swapping is rolled back by Recover
+	if err := f.writeJournal(journal{Swap: true}); err != nil {
+		removeAll(staged...)
+		return fmt.Errorf("could not write journal: %w", err)
+	}
+	err := swap(map[string]string{
+		f.vaultFolder(): stagedVault,
+		f.siteFile():    stagedSiteFile,
+		f.configFile():  stagedConfigFile,
+	})
+	if err != nil {
+		if rerr := f.recoverJournal(); rerr != nil {
+			return fmt.Errorf("%w, could not roll back: %s", err, rerr.Error())
+		}
+		return err
+	}
+	if err = f.commitJournal(); err != nil {
+		return err
+	}
+	removeAll(f.vaultFolder()+backupSuffix, f.siteFile()+backupSuffix, f.configFile()+backupSuffix)
+	return nil
+}
+
+// Write the new vault folder, sites.json and masterpass to the staged paths
+func stage(stagedVault, stagedSiteFile, stagedConfigFile string, config, index []byte, entries map[string][]byte) error {
+	// a vault without sites still needs the folder to be swapped in
+	if err := os.MkdirAll(stagedVault, 0700); err != nil {
+		return fmt.Errorf("could not create vault folder: %w", err)
+	}
+	for name, data := range entries {
+		if err := io.CheckStoredSiteName(name); err != nil {
+			return err
+		}
+		encFilePath := filepath.Join(stagedVault, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(encFilePath), 0700); err != nil {
+			return fmt.Errorf("could not create subdirectory: %w", err)
+		}
+		if err := io.WriteFileAtomic(encFilePath, data, 0600); err != nil {
+			return fmt.Errorf("could not write password of %s: %w", name, err)
+		}
+	}
+	if err := io.WriteFileAtomic(stagedSiteFile, index, 0600); err != nil {
+		return fmt.Errorf("could not write site file: %w", err)
+	}
+	if err := io.WriteFileAtomic(stagedConfigFile, config, 0600); err != nil {
+		return fmt.Errorf("could not write config file: %w", err)
+	}
+	return nil
+}
+
+// rename records a completed rename so it can be undone
+type rename struct {
+	from string
+	to   string
+}
+
+// Move every live path aside and its staged replacement into place.
+// All renames done so far are reverted in reverse order on the first failure.
+func swap(staged map[string]string) (err error) {
+	var done []rename
+	defer func() {
+		if err == nil {
+			return
+		}
+		var failed []string
+		for i := len(done) - 1; i >= 0; i-- {
+			if rerr := os.Rename(done[i].to, done[i].from); rerr != nil {
+				failed = append(failed, rerr.Error())
+			}
+		}
+		if len(failed) > 0 {
+			err = fmt.Errorf("%w, could not restore: %s", err, strings.Join(failed, ", "))
+		}
+	}()
+
+	for live := range staged {
+		if err = os.Rename(live, live+backupSuffix); err != nil {
+			return
+		}
+		done = append(done, rename{from: live, to: live + backupSuffix})
+	}
+	for live, stage := range staged {
+		if err = os.Rename(stage, live); err != nil {
+			return
+		}
+		done = append(done, rename{from: stage, to: live})
+	}
+	return
+}
+
+// Remove everything staged so far
+func removeAll(paths ...string) {
+	for _, p := range paths {
+		os.RemoveAll(p)
+	}
+}
@@ -0,0 +1,115 @@
+package vault
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/jeremyphua/mypass/io"
+)
+
+func TestCreateMakesVaultFolder(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mypass")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Create(NewFileStorage(dir, DefaultFileOptions()), testPassword); err != nil {
+		t.Fatal(err)
+	}
+	// a vault without sites has the folder too
+	info, err := os.Stat(filepath.Join(dir, io.VaultFolderName))
+	if err != nil || !info.IsDir() {
+		t.Fatalf("vault folder after Create(): %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0700 {
+		t.Errorf("vault folder has mode %v, want 0700", info.Mode().Perm())
+	}
+}
+
+func TestEmptyGroupFoldersAreRemoved(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      func(v *Vault) error
+		gone    []string
+		remains []string
+	}{
+		{
+			name:    "delete",
+			op:      func(v *Vault) error { return v.Delete("a/b/c") },
+			gone:    []string{"a/b"},
+			remains: []string{"a", "a/d", "work/mail"},
+		},
+		{
+			name:    "delete last site of a group",
+			op:      func(v *Vault) error { return v.Delete("work/mail") },
+			gone:    []string{"work"},
+			remains: []string{"a/b/c", "a/d"},
+		},
+		{
+			name:    "rename",
+			op:      func(v *Vault) error { return v.Rename("a/b/c", "x/y") },
+			gone:    []string{"a/b"},
+			remains: []string{"a/d", "x/y"},
+		},
+		{
+			name:    "rename within a group",
+			op:      func(v *Vault) error { return v.Rename("work/mail", "work/email") },
+			remains: []string{"work/email"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newFileVault(t)
+			v := openUnlocked(t, NewFileStorage(dir, DefaultFileOptions()), testPassword)
+			for _, name := range []string{"a/b/c", "a/d"} {
+				if err := v.Put(site(name), "secret"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := tt.op(v); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tt.gone {
+				if _, err := os.Stat(entryPath(dir, name)); !os.IsNotExist(err) {
+					t.Errorf("%s still exists: %v", name, err)
+				}
+			}
+			for _, name := range tt.remains {
+				if _, err := os.Stat(entryPath(dir, name)); err != nil {
+					t.Errorf("%s is missing: %v", name, err)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dir, io.VaultFolderName)); err != nil {
+				t.Errorf("vault folder is missing: %v", err)
+			}
+		})
+	}
+}
+
+func TestRollbackRestoresRemovedGroupFolders(t *testing.T) {
+	dir := newFileVault(t)
+	s := NewFileStorage(dir, DefaultFileOptions())
+	failed := errors.New("failed")
+	err := s.Atomic([]string{"work/mail"}, func() error {
+		if err := s.RemoveEntry("work/mail"); err != nil {
+			return err
+		}
+		if _, err := os.Stat(entryPath(dir, "work")); !os.IsNotExist(err) {
+			t.Errorf("work still exists: %v", err)
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Atomic() = %v, want %v", err, failed)
+	}
+	v := openUnlocked(t, s, testPassword)
+	if _, password, err := v.Get("work/mail"); err != nil || password != "secret" {
+		t.Fatalf("Get() = %q, %v after rollback", password, err)
+	}
+}
+
+func entryPath(dir, name string) string {
+	return filepath.Join(dir, io.VaultFolderName, filepath.FromSlash(name))
+}
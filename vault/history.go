@@ -0,0 +1,111 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/pc"
+)
+
+// DefaultHistoryLimit is the number of previous passwords kept per site unless configured otherwise
+const DefaultHistoryLimit = 10
+
+// ErrRevisionNotFound is returned when asking for a previous password a site does not have
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// HistoryLimit returns the number of previous passwords kept per site
+func (v *Vault) HistoryLimit() int {
+	if v.config.HistoryLimit == nil {
+		return DefaultHistoryLimit
+	}
+	return *v.config.HistoryLimit
+}
+
+// SetHistoryLimit changes the number of previous passwords kept per site,
+// dropping the oldest ones of sites that have more
+func (v *Vault) SetHistoryLimit(limit int) error {
+	if limit < 0 {
+		return fmt.Errorf("history limit cannot be negative: %d", limit)
+	}
+	return v.atomic(nil, func() error {
+		sites, err := v.readSites()
+		if err != nil {
+			return err
+		}
+		for i := range sites {
+			if len(sites[i].History) > limit {
+				sites[i].History = sites[i].History[:limit]
+			}
+		}
+		if err = v.writeSites(sites); err != nil {
+			return err
+		}
+		// the vault keeps its config if writing it fails
+		c := v.config
+		c.HistoryLimit = &limit
+		if err = v.writeConfig(c); err != nil {
+			return err
+		}
+		v.config = c
+		return nil
+	})
+}
+
+// Revision returns a previous password of a site, 1 being the one replaced last
+func (v *Vault) Revision(name string, n int) (io.Revision, string, error) {
+	if v.IsLocked() {
+		return io.Revision{}, "", ErrLocked
+	}
+	si, err := v.Site(name)
+	if err != nil {
+		return io.Revision{}, "", err
+	}
+	if n < 1 || n > len(si.History) {
+		return io.Revision{}, "", fmt.Errorf("%w: %s has %d previous passwords", ErrRevisionNotFound, si.Name, len(si.History))
+	}
+	rev := si.History[n-1]
+	password, ok := pc.BoxOpen(rev.Sealed, &rev.PubKey, v.masterPrivKey)
+	if !ok {
+		return rev, "", fmt.Errorf("error decrypting revision %d of %s", n, si.Name)
+	}
+	return rev, string(password), nil
+}
+
+// History of a site once its current password is replaced
+func (v *Vault) pushHistory(previous io.SiteInfo) ([]io.Revision, error) {
+	sealed, err := v.readEntry(previous.Name)
+	if errors.Is(err, os.ErrNotExist) {
+		return previous.History, nil
+	} else if err != nil {
+		return nil, err
+	}
+	rev := io.Revision{PubKey: previous.PubKey, Sealed: sealed, Replaced: time.Now().UTC()}
+	history := append([]io.Revision{rev}, previous.History...)
+	if limit := v.HistoryLimit(); len(history) > limit {
+		history = history[:limit]
+	}
+	return history, nil
+}
+
+// Re-seal the previous passwords of a site to a new master public key
+func (v *Vault) resealHistory(history []io.Revision, masterPub *[32]byte) ([]io.Revision, error) {
+	if history == nil {
+		return nil, nil
+	}
+	resealed := make([]io.Revision, len(history))
+	for i, rev := range history {
+		password, ok := pc.BoxOpen(rev.Sealed, &rev.PubKey, v.masterPrivKey)
+		if !ok {
+			return nil, fmt.Errorf("could not decrypt revision %d", i+1)
+		}
+		si, sealed, err := pc.ReEncrypt(io.SiteInfo{}, string(password), masterPub)
+		if err != nil {
+			return nil, err
+		}
+		resealed[i] = io.Revision{PubKey: si.PubKey, Sealed: sealed, Replaced: rev.Replaced}
+	}
+	return resealed, nil
+}
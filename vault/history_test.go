@@ -0,0 +1,110 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// failingConfigStorage fails to write the config once fail is set
+type failingConfigStorage struct {
+	*MemStorage
+	fail bool
+}
+
+var errWriteConfig = errors.New("disk full")
+
+func (s *failingConfigStorage) WriteConfig(data []byte) error {
+	if s.fail {
+		return errWriteConfig
+	}
+	return s.MemStorage.WriteConfig(data)
+}
+
+// Give bank the passwords pw1 to pwN, pwN being the current one
+func putPasswords(t *testing.T, v *Vault, n int) {
+	t.Helper()
+	for i := 1; i <= n; i++ {
+		if err := v.Put(site("bank"), fmt.Sprintf("pw%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	v, _ := newTestVault(t)
+	putPasswords(t, v, 4)
+	for n, want := range map[int]string{1: "pw3", 2: "pw2", 3: "pw1"} {
+		if _, got, err := v.Revision("bank", n); err != nil || got != want {
+			t.Errorf("Revision(%d) = %q, %v, want %q", n, got, err, want)
+		}
+	}
+	for _, n := range []int{0, 4} {
+		if _, _, err := v.Revision("bank", n); !errors.Is(err, ErrRevisionNotFound) {
+			t.Errorf("Revision(%d) = %v, want ErrRevisionNotFound", n, err)
+		}
+	}
+}
+
+func TestSetHistoryLimit(t *testing.T) {
+	v, s := newTestVault(t)
+	if v.HistoryLimit() != DefaultHistoryLimit {
+		t.Fatalf("HistoryLimit() = %d, want %d", v.HistoryLimit(), DefaultHistoryLimit)
+	}
+	putPasswords(t, v, 5)
+	if err := v.SetHistoryLimit(2); err != nil {
+		t.Fatal(err)
+	}
+	// the limit is saved and the oldest passwords are dropped
+	v = openUnlocked(t, s, testPassword)
+	if v.HistoryLimit() != 2 {
+		t.Fatalf("HistoryLimit() = %d after reopening, want 2", v.HistoryLimit())
+	}
+	si, err := v.Site("bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(si.History) != 2 {
+		t.Fatalf("bank has %d previous passwords, want 2", len(si.History))
+	}
+	if _, got, err := v.Revision("bank", 2); err != nil || got != "pw3" {
+		t.Fatalf("Revision(2) = %q, %v, want pw3", got, err)
+	}
+	// new passwords keep to the limit
+	if err = v.Put(site("bank"), "pw6"); err != nil {
+		t.Fatal(err)
+	}
+	if si, err = v.Site("bank"); err != nil || len(si.History) != 2 {
+		t.Fatalf("bank has %d previous passwords after a change, want 2: %v", len(si.History), err)
+	}
+
+	if err = v.SetHistoryLimit(0); err != nil {
+		t.Fatal(err)
+	}
+	if si, err = v.Site("bank"); err != nil || len(si.History) != 0 {
+		t.Fatalf("bank has %d previous passwords with no history kept: %v", len(si.History), err)
+	}
+	if err = v.SetHistoryLimit(-1); err == nil {
+		t.Fatal("SetHistoryLimit(-1) succeeded")
+	}
+}
+
+func TestSetHistoryLimitKeepsConfigOnFailure(t *testing.T) {
+	s := &failingConfigStorage{MemStorage: NewMemStorage()}
+	v, err := Create(s, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	putPasswords(t, v, 4)
+	s.fail = true
+	if err = v.SetHistoryLimit(1); !errors.Is(err, errWriteConfig) {
+		t.Fatalf("SetHistoryLimit() = %v, want %v", err, errWriteConfig)
+	}
+	if v.HistoryLimit() != DefaultHistoryLimit {
+		t.Errorf("HistoryLimit() = %d after a failed write, want %d", v.HistoryLimit(), DefaultHistoryLimit)
+	}
+	// the sites were rolled back along with it
+	if si, err := v.Site("bank"); err != nil || len(si.History) != 3 {
+		t.Errorf("bank has %d previous passwords after a failed write, want 3: %v", len(si.History), err)
+	}
+}
@@ -0,0 +1,187 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/pc"
+)
+
+// contents of sites.json, either the list of sites or, once encrypted, the sealed list
+type indexFile struct {
+	Version int
+	Sites   io.SiteFile `json:",omitempty"`
+	Sealed  []byte      `json:",omitempty"`
+}
+
+// IndexEncrypted reports whether the site names and usernames can only be read after unlocking
+func (v *Vault) IndexEncrypted() bool {
+	return v.config.EncryptedIndex
+}
+
+// EncryptIndex seals the index with a key derived from the master private key and renames
+// every entry after a keyed hash of its site name, or reverts both when encrypt is false.
+// The new index and entries replace the current ones in a single Storage.Replace. When encrypting,
+// the backups in the storage still reveal the site names. They are kept unless removeBackups is set:
+// then they are removed once the index is encrypted and their paths returned.
+func (v *Vault) EncryptIndex(encrypt, removeBackups bool) ([]string, error) {
+	if v.IsLocked() {
+		return nil, ErrLocked
+	}
+	release, err := v.storage.Acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err = v.refreshConfig(); err != nil {
+		return nil, err
+	}
+	if v.config.EncryptedIndex == encrypt {
+		return nil, nil
+	}
+
+	sites, err := v.readSites()
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string][]byte, len(sites))
+	for _, siteInfo := range sites {
+		encrypted, err := v.readEntry(siteInfo.Name)
+		if err != nil {
+			return nil, err
+		}
+		entries[entryName(siteInfo.Name, encrypt, v.masterPrivKey)] = entryData(encrypted)
+	}
+
+	c := v.config
+	c.EncryptedIndex = encrypt
+	config, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal config file: %w", err)
+	}
+	index, err := marshalSites(sites, encrypt, v.masterPrivKey)
+	if err != nil {
+		return nil, err
+	}
+	if err = v.storage.Replace(config, index, entries); err != nil {
+		return nil, fmt.Errorf("could not swap in the new index, the previous vault was restored: %w", err)
+	}
+	v.config = c
+	if !encrypt || !removeBackups {
+		return nil, nil
+	}
+	return v.removeBackups("revealing the site names")
+}
+
+// Name of the stored entry of a site. Entries of an encrypted index are named after
+// a keyed hash of the site name so that the vault folder does not reveal the sites.
+func (v *Vault) entryName(name string) (string, error) {
+	if v.config.EncryptedIndex && v.IsLocked() {
+		return "", ErrLocked
+	}
+	return entryName(name, v.config.EncryptedIndex, v.masterPrivKey), nil
+}
+
+func entryName(name string, encrypted bool, masterPrivKey *[32]byte) string {
+	if !encrypted {
+		return name
+	}
+	_, nameKey := pc.DeriveIndexKeys(masterPrivKey)
+	return pc.EntryID(nameKey, name)
+}
+
+// Read the sealed password of a site
+func (v *Vault) readEntry(name string) ([]byte, error) {
+	entry, err := v.entryName(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := v.storage.ReadEntry(entry)
+	if err != nil {
+		return nil, fmt.Errorf("could not read password of %s: %w", name, err)
+	}
+	encrypted, err := openEntryData(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not read password of %s: %w", name, err)
+	}
+	return encrypted, nil
+}
+
+// Contents of a password file: the format version followed by the sealed password
+func entryData(encrypted []byte) []byte {
+	return append([]byte{entryVersion}, encrypted...)
+}
+
+func openEntryData(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("password file is empty")
+	}
+	if err := checkVersion(int(data[0]), entryVersion); err != nil {
+		return nil, err
+	}
+	return data[1:], nil
+}
+
+// Names of the stored entries of the given sites, as passed to Storage.Atomic
+func (v *Vault) entryNames(names ...string) ([]string, error) {
+	entries := make([]string, len(names))
+	for i, name := range names {
+		entry, err := v.entryName(name)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+func marshalSites(s io.SiteFile, encrypted bool, masterPrivKey *[32]byte) ([]byte, error) {
+	index := indexFile{Version: indexVersion, Sites: s}
+	if encrypted {
+		if masterPrivKey == nil {
+			return nil, ErrLocked
+		}
+		data, err := json.Marshal(s)
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal site info: %w", err)
+		}
+		indexKey, _ := pc.DeriveIndexKeys(masterPrivKey)
+		if index.Sealed, err = pc.SecretboxSeal(indexKey, data); err != nil {
+			return nil, fmt.Errorf("could not encrypt site info: %w", err)
+		}
+		index.Sites = nil
+	}
+	data, err := json.MarshalIndent(index, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal site info: %w", err)
+	}
+	return data, nil
+}
+
+func unmarshalSites(data []byte, encrypted bool, masterPrivKey *[32]byte) (io.SiteFile, error) {
+	var index indexFile
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("could not unmarshal site info: %w", err)
+	}
+	if err := checkVersion(index.Version, indexVersion); err != nil {
+		return nil, err
+	}
+	if !encrypted {
+		return index.Sites, nil
+	}
+	if masterPrivKey == nil {
+		return nil, ErrLocked
+	}
+	indexKey, _ := pc.DeriveIndexKeys(masterPrivKey)
+	data, ok := pc.SecretboxOpen(indexKey, index.Sealed)
+	if !ok {
+		return nil, errors.New("could not decrypt site info")
+	}
+	var s io.SiteFile
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("could not unmarshal site info: %w", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,92 @@
+package vault
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jeremyphua/mypass/io"
+)
+
+func TestEncryptIndexLeavesNoSiteNames(t *testing.T) {
+	dir := newFileVault(t)
+	s := NewFileStorage(dir, DefaultFileOptions())
+	v := openUnlocked(t, s, testPassword)
+	if err := v.Put(io.SiteInfo{Name: "acme-bank/checking", Username: "alice@example.com"}, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	// a rolling backup and a migration backup, both taken before the index was encrypted
+	if err := v.Rename("acme-bank/checking", "acme-bank/savings"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Backup("pre-v3-20260101-000000"); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := v.EncryptIndex(true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 3 {
+		t.Errorf("EncryptIndex() removed %v, want 3 backups", removed)
+	}
+
+	secrets := []string{"acme-bank", "checking", "savings", "alice@example.com", "work/mail"}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files := map[string][]byte{rel: data}
+		if strings.HasSuffix(path, ".tar.gz") {
+			for _, f := range readTar(t, data) {
+				files[rel+":"+f.name] = f.data
+			}
+		}
+		for name, data := range files {
+			for _, secret := range secrets {
+				if strings.Contains(filepath.ToSlash(name), secret) || bytes.Contains(data, []byte(secret)) {
+					t.Errorf("%s reveals %q", name, secret)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// decrypting the index keeps the backups taken while it was encrypted
+	if err = v.Delete("work/mail"); err != nil {
+		t.Fatal(err)
+	}
+	if removed, err = v.EncryptIndex(false, true); err != nil || len(removed) > 0 {
+		t.Fatalf("EncryptIndex(false, true) = %v, %v, want no backup removed", removed, err)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(dir, BackupFolderName, AutoBackupFolderName, "*")); len(backups) != 1 {
+		t.Errorf("backups after decrypting the index: %v, want the one taken by delete", backups)
+	}
+}
+
+func TestEncryptIndexKeepsBackups(t *testing.T) {
+	dir := newFileVault(t)
+	v := openUnlocked(t, NewFileStorage(dir, DefaultFileOptions()), testPassword)
+	if err := v.Rename("work/mail", "work/email"); err != nil {
+		t.Fatal(err)
+	}
+	if removed, err := v.EncryptIndex(true, false); err != nil || len(removed) > 0 {
+		t.Fatalf("EncryptIndex(true, false) = %v, %v, want no backup removed", removed, err)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(dir, BackupFolderName, AutoBackupFolderName, "*")); len(backups) != 1 {
+		t.Errorf("backups after encrypting the index: %v, want the one taken by rename", backups)
+	}
+}
@@ -0,0 +1,179 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/jeremyphua/mypass/io"
+)
+
+const (
+	// folder in the pass dir holding the journal of the operation in progress
+	journalFolderName = "journal"
+	// the operation is only in progress while this file exists in the journal folder
+	journalFileName = "journal.json"
+)
+
+// journal lists what has to be restored if a multi-file operation is interrupted
+type journal struct {
+	// files changed by the operation
+	Files []journalFile
+	// set while Replace swaps the staged vault in
+	Swap bool
+}
+
+// journalFile is a file of the pass dir saved before an operation changed it
+type journalFile struct {
+	// path relative to the pass dir
+	Path string
+	// name of the copy in the journal folder, empty if the file did not exist
+	Backup string
+}
+
+func (f *FileStorage) journalFolder() string {
+	return filepath.Join(f.dir, journalFolderName)
+}
+
+// Atomic saves sites.json and the given entries to the journal before running fn,
+// and restores them if fn fails. An operation interrupted by a crash is rolled back by Recover.
+func (f *FileStorage) Atomic(entries []string, fn func() error) error {
+	paths := []string{io.SiteFileName}
+	for _, name := range entries {
+		if err := io.CheckStoredSiteName(name); err != nil {
+			return err
+		}
+		paths = append(paths, filepath.Join(io.VaultFolderName, filepath.FromSlash(name)))
+	}
+	if err := f.beginJournal(paths); err != nil {
+		return fmt.Errorf("could not write journal: %w", err)
+	}
+	if err := fn(); err != nil {
+		if rerr := f.recoverJournal(); rerr != nil {
+			return fmt.Errorf("%w, could not roll back: %s", err, rerr.Error())
+		}
+		return err
+	}
+	return f.commitJournal()
+}
+
+// Recover rolls back the operation recorded in the journal, if any.
+// The vault is only locked when there is something to roll back.
+func (f *FileStorage) Recover() error {
+	if _, err := os.Stat(filepath.Join(f.journalFolder(), journalFileName)); err != nil {
+		return nil
+	}
+	release, err := f.Acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+	return f.recoverJournal()
+}
+
+// Roll back the journal, the caller holds the lock
+func (f *FileStorage) recoverJournal() error {
+	j, err := f.readJournal()
+	if errors.Is(err, os.ErrNotExist) {
+		// nothing was in progress, only leftovers of a committed journal may remain
+		return os.RemoveAll(f.journalFolder())
+	}
+	if err != nil {
+		return fmt.Errorf("could not read journal: %w", err)
+	}
+
+	if j.Swap {
+		for _, live := range []string{f.vaultFolder(), f.siteFile(), f.configFile()} {
+			if _, err := os.Stat(live + backupSuffix); err != nil {
+				continue
+			}
+			if err := os.RemoveAll(live); err != nil {
+				return err
+			}
+			if err := os.Rename(live+backupSuffix, live); err != nil {
+				return err
+			}
+		}
+		removeAll(f.vaultFolder()+stagedSuffix, f.siteFile()+stagedSuffix, f.configFile()+stagedSuffix)
+	}
+	for _, jf := range j.Files {
+		path := filepath.Join(f.dir, jf.Path)
+		if jf.Backup == "" {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(f.journalFolder(), jf.Backup))
+		if err != nil {
+			return err
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+		if err = io.WriteFileAtomic(path, data, 0600); err != nil {
+			return err
+		}
+	}
+	return f.commitJournal()
+}
+
+// Copy every existing path to the journal folder, then write the journal itself.
+// Until the journal file exists there is nothing to roll back.
+func (f *FileStorage) beginJournal(paths []string) error {
+	if err := f.recoverJournal(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(f.journalFolder(), 0700); err != nil {
+		return err
+	}
+	var j journal
+	for i, p := range paths {
+		jf := journalFile{Path: p}
+		data, err := ioutil.ReadFile(filepath.Join(f.dir, p))
+		if err == nil {
+			jf.Backup = strconv.Itoa(i)
+			if err = io.WriteFileAtomic(filepath.Join(f.journalFolder(), jf.Backup), data, 0600); err != nil {
+				return err
+			}
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		j.Files = append(j.Files, jf)
+	}
+	return f.writeJournal(j)
+}
+
+func (f *FileStorage) writeJournal(j journal) error {
+	if err := os.MkdirAll(f.journalFolder(), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(j, "", "\t")
+	if err != nil {
+		return err
+	}
+	return io.WriteFileAtomic(filepath.Join(f.journalFolder(), journalFileName), data, 0600)
+}
+
+func (f *FileStorage) readJournal() (j journal, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(f.journalFolder(), journalFileName))
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &j)
+	return
+}
+
+// Removing the journal file is the commit point, the backups are cleaned up afterwards
+func (f *FileStorage) commitJournal() error {
+	err := os.Remove(filepath.Join(f.journalFolder(), journalFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove journal: %w", err)
+	}
+	os.RemoveAll(f.journalFolder())
+	return nil
+}
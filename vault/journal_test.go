@@ -0,0 +1,159 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeremyphua/mypass/io"
+)
+
+// crash runs fn and stops it with a panic like a killed process, leaving the journal behind
+func crash(t *testing.T, fn func(crash func()) error) {
+	t.Helper()
+	type crashed struct{}
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(crashed); !ok {
+				panic(r)
+			}
+		}
+	}()
+	if err := fn(func() { panic(crashed{}) }); err != nil {
+		t.Fatal(err)
+	}
+	t.Fatal("the operation did not crash")
+}
+
+func TestRecoverInterruptedOperation(t *testing.T) {
+	tests := []struct {
+		name      string
+		interrupt func(t *testing.T, s *FileStorage)
+	}{
+		{"add", func(t *testing.T, s *FileStorage) {
+			crash(t, func(crash func()) error {
+				return s.Atomic([]string{"work/new"}, func() error {
+					if err := s.WriteEntry("work/new", []byte("sealed")); err != nil {
+						return err
+					}
+					if err := s.WriteIndex([]byte("{")); err != nil {
+						return err
+					}
+					crash()
+					return nil
+				})
+			})
+		}},
+		{"delete", func(t *testing.T, s *FileStorage) {
+			crash(t, func(crash func()) error {
+				return s.Atomic([]string{"work/mail"}, func() error {
+					if err := s.RemoveEntry("work/mail"); err != nil {
+						return err
+					}
+					crash()
+					return nil
+				})
+			})
+		}},
+		{"rename", func(t *testing.T, s *FileStorage) {
+			crash(t, func(crash func()) error {
+				return s.Atomic([]string{"work/mail", "home/mail"}, func() error {
+					if err := s.RenameEntry("work/mail", "home/mail"); err != nil {
+						return err
+					}
+					crash()
+					return nil
+				})
+			})
+		}},
+		{"replace while swapping", func(t *testing.T, s *FileStorage) {
+			// the live index and vault folder were moved aside, only the index was swapped in
+			if err := s.writeJournal(journal{Swap: true}); err != nil {
+				t.Fatal(err)
+			}
+			for _, live := range []string{s.siteFile(), s.vaultFolder()} {
+				if err := os.Rename(live, live+backupSuffix); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(s.siteFile(), []byte("{"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Mkdir(s.vaultFolder()+stagedSuffix, 0700); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"leftovers of a committed journal", func(t *testing.T, s *FileStorage) {
+			if err := os.MkdirAll(s.journalFolder(), 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(s.journalFolder(), "0"), []byte("{"), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newFileVault(t)
+			tt.interrupt(t, NewFileStorage(dir, DefaultFileOptions()))
+
+			// the next mypass process rolls the operation back when opening the vault
+			s := NewFileStorage(dir, DefaultFileOptions())
+			v := openUnlocked(t, s, testPassword)
+			sites, err := v.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sites) != 1 {
+				t.Errorf("List() has %d sites, want 1", len(sites))
+			}
+			if _, password, err := v.Get("work/mail"); err != nil || password != "secret" {
+				t.Errorf("Get() = %q, %v after recovery", password, err)
+			}
+			entries, err := v.Entries()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0] != "work/mail" {
+				t.Errorf("Entries() = %v after recovery, want [work/mail]", entries)
+			}
+			// the next operation starts from a clean journal
+			if err = v.Put(site("work/mail"), "secret"); err != nil {
+				t.Fatal(err)
+			}
+			for _, leftover := range []string{
+				s.journalFolder(),
+				s.siteFile() + backupSuffix,
+				s.vaultFolder() + backupSuffix,
+				s.vaultFolder() + stagedSuffix,
+			} {
+				if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+					t.Errorf("%s was not cleaned up: %v", leftover, err)
+				}
+			}
+		})
+	}
+}
+
+func TestReplaceLeavesNoStagedFiles(t *testing.T) {
+	dir := newFileVault(t)
+	s := NewFileStorage(dir, DefaultFileOptions())
+	v := openUnlocked(t, s, testPassword)
+	if _, _, err := v.RotateKeys(false); err != nil {
+		t.Fatal(err)
+	}
+	v = openUnlocked(t, NewFileStorage(dir, DefaultFileOptions()), testPassword)
+	if _, password, err := v.Get("work/mail"); err != nil || password != "secret" {
+		t.Fatalf("Get() = %q, %v after RotateKeys", password, err)
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{io.ConfigFileName: true, io.SiteFileName: true, io.VaultFolderName: true, BackupFolderName: true, lockFileName: true}
+	for _, m := range matches {
+		if !want[filepath.Base(m)] {
+			t.Errorf("unexpected %s in the pass dir after RotateKeys", filepath.Base(m))
+		}
+	}
+}
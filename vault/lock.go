@@ -0,0 +1,102 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lock file in the pass dir, holds the PID of the process owning the lock
+const lockFileName = "lock"
+
+// ErrInUse is returned when another process did not release the vault lock in time
+var ErrInUse = errors.New("vault is locked")
+
+// interval between two attempts to take the lock
+const lockRetryInterval = 100 * time.Millisecond
+
+func (f *FileStorage) lockFile() string {
+	return filepath.Join(f.dir, lockFileName)
+}
+
+// Acquire takes an advisory lock on the lock file of the pass dir, waiting at most LockTimeout
+// for other processes to release it. Acquiring again from the same FileStorage only counts the holders.
+func (f *FileStorage) Acquire() (release func(), err error) {
+	return f.acquire(f.LockTimeout)
+}
+
+// TryAcquire takes the lock like Acquire, without waiting for other processes
+func (f *FileStorage) TryAcquire() (release func(), err error) {
+	return f.acquire(0)
+}
+
+func (f *FileStorage) acquire(timeout time.Duration) (release func(), err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.holders > 0 {
+		f.holders++
+		return f.release, nil
+	}
+
+	file, err := os.OpenFile(f.lockFile(), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open lock file: %w", err)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLock(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("could not lock vault: %w", err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			file.Close()
+			if pid := f.lockOwner(); pid > 0 {
+				return nil, fmt.Errorf("%w by PID %d", ErrInUse, pid)
+			}
+			return nil, ErrInUse
+		}
+		time.Sleep(lockRetryInterval)
+	}
+
+	// record the owner so that waiting processes can tell who holds the lock
+	file.Truncate(0)
+	file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	f.lock = file
+	f.holders = 1
+	return f.release, nil
+}
+
+func (f *FileStorage) release() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.holders == 0 {
+		return
+	}
+	f.holders--
+	if f.holders > 0 {
+		return
+	}
+	f.lock.Truncate(0)
+	unlock(f.lock)
+	f.lock.Close()
+	f.lock = nil
+}
+
+// PID written to the lock file by its current owner, 0 if unknown
+func (f *FileStorage) lockOwner() int {
+	data, err := ioutil.ReadFile(f.lockFile())
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
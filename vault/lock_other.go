@@ -0,0 +1,12 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package vault
+
+import "os"
+
+// Platforms without flock or LockFileEx are not locked against other processes
+func tryLock(file *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlock(file *os.File) {}
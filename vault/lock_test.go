@@ -0,0 +1,91 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows
+
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A second FileStorage on the same pass dir locks its own file, like another mypass process would
+func otherProcess(dir string, timeout time.Duration) *FileStorage {
+	s := NewFileStorage(dir, DefaultFileOptions())
+	s.LockTimeout = timeout
+	return s
+}
+
+func TestLockTimeout(t *testing.T) {
+	dir := newFileVault(t)
+	release, err := NewFileStorage(dir, DefaultFileOptions()).Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	other := otherProcess(dir, 200*time.Millisecond)
+	start := time.Now()
+	_, err = other.Acquire()
+	if !errors.Is(err, ErrInUse) {
+		t.Fatalf("Acquire() = %v, want ErrInUse", err)
+	}
+	if waited := time.Since(start); waited < 200*time.Millisecond {
+		t.Errorf("Acquire() gave up after %s, want 200ms", waited)
+	}
+	// the error tells who holds the vault
+	if pid := fmt.Sprintf("PID %d", os.Getpid()); !strings.Contains(err.Error(), pid) {
+		t.Errorf("error %q does not name %s", err, pid)
+	}
+	if _, err = other.TryAcquire(); !errors.Is(err, ErrInUse) {
+		t.Fatalf("TryAcquire() = %v, want ErrInUse", err)
+	}
+	// operations of the other vault fail rather than write
+	v := openUnlocked(t, other, testPassword)
+	if err = v.Add(site("bank"), "pw"); !errors.Is(err, ErrInUse) {
+		t.Fatalf("Add() = %v, want ErrInUse", err)
+	}
+}
+
+func TestLockWaitsForRelease(t *testing.T) {
+	dir := newFileVault(t)
+	release, err := NewFileStorage(dir, DefaultFileOptions()).Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		release()
+	}()
+	otherRelease, err := otherProcess(dir, 5*time.Second).Acquire()
+	if err != nil {
+		t.Fatalf("Acquire() = %v after the lock was released", err)
+	}
+	otherRelease()
+}
+
+func TestLockIsReentrant(t *testing.T) {
+	dir := newFileVault(t)
+	s := NewFileStorage(dir, DefaultFileOptions())
+	release, err := s.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the storage already holds the lock, so this does not wait for itself
+	inner, err := s.TryAcquire()
+	if err != nil {
+		t.Fatalf("Acquire() while holding the lock = %v", err)
+	}
+	inner()
+	if _, err = otherProcess(dir, 0).Acquire(); !errors.Is(err, ErrInUse) {
+		t.Fatalf("lock released while still held: %v", err)
+	}
+	release()
+	otherRelease, err := otherProcess(dir, 0).Acquire()
+	if err != nil {
+		t.Fatalf("Acquire() = %v after every holder released", err)
+	}
+	otherRelease()
+}
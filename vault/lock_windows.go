@@ -0,0 +1,27 @@
+//go:build windows
+
+package vault
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// The locked byte lies far beyond the PID written to the file, so other processes can still read it
+var lockRange = windows.Overlapped{OffsetHigh: 1}
+
+// Take an exclusive lock without blocking, reports false if another process holds it
+func tryLock(file *os.File) (bool, error) {
+	ol := lockRange
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &ol)
+	if err == windows.ERROR_LOCK_VIOLATION {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlock(file *os.File) {
+	ol := lockRange
+	windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &ol)
+}
@@ -0,0 +1,169 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// MemStorage keeps a vault in memory. It is meant for tests and for programs
+// that only need a throwaway vault.
+type MemStorage struct {
+	// held between Acquire and release
+	opMu sync.Mutex
+
+	mu      sync.Mutex
+	config  []byte
+	index   []byte
+	entries map[string][]byte
+}
+
+// NewMemStorage returns an empty MemStorage, use Create to initialize a vault in it
+func NewMemStorage() *MemStorage {
+	return &MemStorage{entries: map[string][]byte{}}
+}
+
+func (m *MemStorage) ReadConfig() ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return read("config", m.config)
+}
+
+func (m *MemStorage) WriteConfig(data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config = clone(data)
+	return nil
+}
+
+func (m *MemStorage) ReadIndex() ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return read("index", m.index)
+}
+
+func (m *MemStorage) WriteIndex(data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.index = clone(data)
+	return nil
+}
+
+func (m *MemStorage) ReadEntry(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return read(name, m.entries[name])
+}
+
+func (m *MemStorage) WriteEntry(name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[name] = clone(data)
+	return nil
+}
+
+func (m *MemStorage) RemoveEntry(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.entries[name]; !ok {
+		return notExist(name)
+	}
+	delete(m.entries, name)
+	return nil
+}
+
+func (m *MemStorage) RenameEntry(oldName, newName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.entries[oldName]
+	if !ok {
+		return notExist(oldName)
+	}
+	delete(m.entries, oldName)
+	m.entries[newName] = data
+	return nil
+}
+
+func (m *MemStorage) ListEntries() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.entries))
+	for name := range m.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (m *MemStorage) Replace(config, index []byte, entries map[string][]byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config = clone(config)
+	m.index = clone(index)
+	m.entries = make(map[string][]byte, len(entries))
+	for name, data := range entries {
+		m.entries[name] = clone(data)
+	}
+	return nil
+}
+
+// Acquire serializes the operations of the vaults sharing this storage
+func (m *MemStorage) Acquire() (func(), error) {
+	m.opMu.Lock()
+	return m.opMu.Unlock, nil
+}
+
+// TryAcquire takes the lock like Acquire, failing with ErrInUse if another vault holds it
+func (m *MemStorage) TryAcquire() (func(), error) {
+	if !m.opMu.TryLock() {
+		return nil, ErrInUse
+	}
+	return m.opMu.Unlock, nil
+}
+
+// Atomic restores the index and the given entries if fn fails
+func (m *MemStorage) Atomic(entries []string, fn func() error) error {
+	m.mu.Lock()
+	index := clone(m.index)
+	saved := make(map[string][]byte, len(entries))
+	for _, name := range entries {
+		saved[name] = clone(m.entries[name])
+	}
+	m.mu.Unlock()
+
+	err := fn()
+	if err == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.index = index
+	for name, data := range saved {
+		if data == nil {
+			delete(m.entries, name)
+		} else {
+			m.entries[name] = data
+		}
+	}
+	return err
+}
+
+func read(name string, data []byte) ([]byte, error) {
+	if data == nil {
+		return nil, notExist(name)
+	}
+	return clone(data), nil
+}
+
+func notExist(name string) error {
+	return fmt.Errorf("%s: %w", name, os.ErrNotExist)
+}
+
+func clone(data []byte) []byte {
+	if data == nil {
+		return nil
+	}
+	return append([]byte{}, data...)
+}
@@ -0,0 +1,189 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jeremyphua/mypass/io"
+)
+
+// CurrentVersion is the on-disk format version written by this mypass.
+// Vaults of older versions are migrated when opened, newer ones are refused.
+const CurrentVersion = 3
+
+// Format versions of sites.json and of the password files,
+// bumped along with CurrentVersion when their own format changes
+const (
+	indexVersion = 3
+	entryVersion = 1
+)
+
+// ErrNewerVersion is returned when opening a vault written by a newer mypass
+var ErrNewerVersion = errors.New("vault was written by a newer version of mypass")
+
+// Backuper is implemented by storages that can copy the whole vault aside,
+// Open takes a backup before migrating a vault to a newer format
+type Backuper interface {
+	// Backup copies the vault and returns where the copy is
+	Backup(label string) (string, error)
+}
+
+// vaultFiles are the raw contents of a vault being migrated
+type vaultFiles struct {
+	config  io.ConfigFile
+	index   []byte
+	entries map[string][]byte
+}
+
+// migration upgrades the files of a vault from version from to from+1
+type migration struct {
+	from        int
+	description string
+	upgrade     func(files *vaultFiles) error
+}
+
+// migrations upgrade a vault step by step, one per version
+var migrations = []migration{
+	{from: 0, description: "add version headers to sites.json and the password files", upgrade: addVersionHeaders},
+	{from: 1, description: "add password history to sites.json", upgrade: addPasswordHistory},
+	{from: 2, description: "add timestamps to sites.json", upgrade: addTimestamps},
+}
+
+// Check that a format version is not newer than the supported one, migrating older ones is up to the caller
+func checkVersion(version, supported int) error {
+	if version > supported {
+		return fmt.Errorf("%w: its format version is %d but this mypass only reads up to version %d, upgrade mypass to open it",
+			ErrNewerVersion, version, supported)
+	}
+	return nil
+}
+
+// Migrate the vault to CurrentVersion, taking a backup first if the storage can.
+// The upgraded config, index and entries replace the current ones in a single Storage.Replace.
+func (v *Vault) migrate() error {
+	release, err := v.storage.Acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+	// another process may have migrated the vault while waiting for the lock
+	if err = v.readConfig(); err != nil || v.config.Version == CurrentVersion {
+		return err
+	}
+
+	from := v.config.Version
+	files, err := v.readFiles()
+	if err != nil {
+		return err
+	}
+	backup := ""
+	if b, ok := v.storage.(Backuper); ok {
+		label := fmt.Sprintf("pre-v%d-%s", CurrentVersion, time.Now().Format("20060102-150405"))
+		if backup, err = b.Backup(label); err != nil {
+			return fmt.Errorf("could not back up the vault before migrating it: %w", err)
+		}
+	}
+	for _, m := range migrations {
+		if m.from < files.config.Version {
+			continue
+		}
+		if err = m.upgrade(files); err != nil {
+			return fmt.Errorf("could not migrate the vault from version %d: %s: %w", m.from, m.description, err)
+		}
+		files.config.Version = m.from + 1
+	}
+	if files.config.Version != CurrentVersion {
+		return fmt.Errorf("no migration of the vault from version %d", files.config.Version)
+	}
+
+	config, err := json.MarshalIndent(files.config, "", "\t")
+	if err != nil {
+		return fmt.Errorf("could not marshal config file: %w", err)
+	}
+	if err = v.storage.Replace(config, files.index, files.entries); err != nil {
+		return fmt.Errorf("could not swap in the migrated vault, the previous vault was restored: %w", err)
+	}
+	v.config = files.config
+	notice := fmt.Sprintf("Migrated the vault from format version %d to %d", from, CurrentVersion)
+	if backup != "" {
+		notice += ", the previous vault was backed up to " + backup
+	}
+	v.notices = append(v.notices, notice)
+	return nil
+}
+
+// Read the config, index and every entry as stored
+func (v *Vault) readFiles() (*vaultFiles, error) {
+	files := &vaultFiles{config: v.config, entries: map[string][]byte{}}
+	var err error
+	if files.index, err = v.storage.ReadIndex(); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, io.ErrVaultNotInitialized
+		}
+		return nil, fmt.Errorf("could not read site file: %w", err)
+	}
+	names, err := v.storage.ListEntries()
+	if err != nil {
+		return nil, fmt.Errorf("could not list the password files: %w", err)
+	}
+	for _, name := range names {
+		if files.entries[name], err = v.storage.ReadEntry(name); err != nil {
+			return nil, fmt.Errorf("could not read password file %s: %w", name, err)
+		}
+	}
+	return files, nil
+}
+
+// Version 0 stored sites.json as a bare list of sites, or as {"Sealed": ...} once encrypted,
+// and the password files as the sealed box only
+func addVersionHeaders(files *vaultFiles) error {
+	index, err := upgradeIndexV0(files.index)
+	if err != nil {
+		return err
+	}
+	files.index = index
+	for name, data := range files.entries {
+		files.entries[name] = append([]byte{1}, data...)
+	}
+	return nil
+}
+
+func upgradeIndexV0(data []byte) ([]byte, error) {
+	var index indexFile
+	if err := json.Unmarshal(data, &index.Sites); err != nil {
+		// an encrypted index was already an object, only the version is missing
+		if err = json.Unmarshal(data, &index); err != nil {
+			return nil, fmt.Errorf("could not unmarshal site info: %w", err)
+		}
+	}
+	index.Version = 1
+	return json.MarshalIndent(index, "", "\t")
+}
+
+// Version 1 had no password history, an index without one reads the same
+func addPasswordHistory(files *vaultFiles) error {
+	return setIndexVersion(files, 2)
+}
+
+// Version 2 had no timestamps, they stay unknown for the existing sites
+func addTimestamps(files *vaultFiles) error {
+	return setIndexVersion(files, 3)
+}
+
+// Bump the version of an index whose sites read the same in the new version
+func setIndexVersion(files *vaultFiles, version int) error {
+	var index indexFile
+	if err := json.Unmarshal(files.index, &index); err != nil {
+		return fmt.Errorf("could not unmarshal site info: %w", err)
+	}
+	index.Version = version
+	data, err := json.MarshalIndent(index, "", "\t")
+	if err != nil {
+		return fmt.Errorf("could not marshal site info: %w", err)
+	}
+	files.index = data
+	return nil
+}
@@ -0,0 +1,243 @@
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/pc"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// Write a vault in the format used before versioning: an unversioned masterpass,
+// sites.json as a bare list and password files holding the sealed box only
+func writeV0Vault(t *testing.T, passwords map[string]string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "mypass")
+	if err := os.MkdirAll(filepath.Join(dir, io.VaultFolderName), 0700); err != nil {
+		t.Fatal(err)
+	}
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt, sealed, err := pc.SealMasterPrivKey(testPassword, pc.LegacyArgon2Params(), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeJSON(t, filepath.Join(dir, io.ConfigFileName), io.ConfigFile{Salt: salt, MasterPrivKeySealed: sealed, MasterPubKey: *pub})
+
+	var sites io.SiteFile
+	for name, password := range passwords {
+		si, encrypted, err := pc.ReEncrypt(io.SiteInfo{Name: name, Username: "user"}, password, pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, io.VaultFolderName, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(path, encrypted, 0600); err != nil {
+			t.Fatal(err)
+		}
+		sites = append(sites, si)
+	}
+	writeJSON(t, filepath.Join(dir, io.SiteFileName), sites)
+	return dir
+}
+
+func writeJSON(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateV0WithLegacyNames(t *testing.T) {
+	passwords := map[string]string{
+		"money/ocbc":    "secret1",
+		"money/my bank": "secret2",
+		"mail (work)":   "secret3",
+	}
+	dir := writeV0Vault(t, passwords)
+
+	v, err := Open(NewFileStorage(dir, DefaultFileOptions()))
+	if err != nil {
+		t.Fatalf("Open() of a v0 vault: %v", err)
+	}
+	if v.config.Version != CurrentVersion {
+		t.Fatalf("Version = %d after migrating, want %d", v.config.Version, CurrentVersion)
+	}
+	if err = v.Unlock(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range passwords {
+		_, got, err := v.Get(name)
+		if err != nil {
+			t.Errorf("Get(%q) after migrating: %v", name, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Get(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	problems, err := v.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := map[string]bool{}
+	for _, p := range problems {
+		if p.Kind != ProblemInvalidName {
+			t.Errorf("unexpected problem after migrating: %s", p)
+			continue
+		}
+		invalid[p.Site] = true
+	}
+	for _, name := range []string{"money/my bank", "mail (work)"} {
+		if !invalid[name] {
+			t.Errorf("Check() did not report the invalid name %q", name)
+		}
+	}
+
+	// sites with invalid names can be renamed and deleted under their stored name
+	if err = v.Rename("money/my bank", "money/my-bank"); err != nil {
+		t.Fatalf("Rename() of a legacy name: %v", err)
+	}
+	if err = v.Delete("mail (work)"); err != nil {
+		t.Fatalf("Delete() of a legacy name: %v", err)
+	}
+	if err = v.Rename("money/ocbc", "money/o cbc"); !errors.Is(err, io.ErrInvalidSiteName) {
+		t.Errorf("Rename() to an invalid name = %v, want ErrInvalidSiteName", err)
+	}
+	if problems, err = v.Check(); err != nil || len(problems) != 0 {
+		t.Errorf("Check() after renaming = %v, %v, want no problems", problems, err)
+	}
+
+	backups, err := filepath.Glob(filepath.Join(dir, BackupFolderName, "pre-v*", io.ConfigFileName))
+	if err != nil || len(backups) != 1 {
+		t.Errorf("expected a backup of the v0 vault, got %v, %v", backups, err)
+	}
+}
+
+func TestOpenRefusesNewerVersion(t *testing.T) {
+	s := NewMemStorage()
+	if _, err := Create(s, testPassword); err != nil {
+		t.Fatal(err)
+	}
+	writeConfigVersion(t, s, CurrentVersion+1)
+	if _, err := Open(s); !errors.Is(err, ErrNewerVersion) {
+		t.Fatalf("Open() of a newer vault = %v, want ErrNewerVersion", err)
+	}
+}
+
+func writeConfigVersion(t *testing.T, s Storage, version int) {
+	t.Helper()
+	c := readTestConfig(t, s)
+	c.Version = version
+	writeJSONConfig(t, s, c)
+}
+
+func TestMigrateOnlyOnce(t *testing.T) {
+	dir := writeV0Vault(t, map[string]string{"bank": "secret"})
+	for i := 0; i < 2; i++ {
+		v, err := Open(NewFileStorage(dir, DefaultFileOptions()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the migration is reported to the caller the first time only
+		if notices := v.Notices(); len(notices) != 1-i {
+			t.Errorf("Open() %d reported %q", i+1, notices)
+		}
+	}
+	backups, err := filepath.Glob(filepath.Join(dir, BackupFolderName, "pre-v*"))
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("backups after opening a v0 vault twice: %v, %v, want 1", backups, err)
+	}
+	// the index and password files carry their format version
+	s := NewFileStorage(dir, DefaultFileOptions())
+	var index struct{ Version int }
+	data, err := s.ReadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(data, &index); err != nil || index.Version != indexVersion {
+		t.Fatalf("sites.json has version %d, %v, want %d", index.Version, err, indexVersion)
+	}
+	if data, err = s.ReadEntry("bank"); err != nil || data[0] != entryVersion {
+		t.Fatalf("password file starts with %d, %v, want version %d", data[0], err, entryVersion)
+	}
+}
+
+func TestMigrateFromEachVersion(t *testing.T) {
+	for from := 1; from < CurrentVersion; from++ {
+		t.Run(fmt.Sprintf("v%d", from), func(t *testing.T) {
+			v, s := newTestVault(t)
+			if err := v.Add(site("bank"), "secret"); err != nil {
+				t.Fatal(err)
+			}
+			writeConfigVersion(t, s, from)
+			writeIndexVersion(t, s, from)
+
+			v, err := Open(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.config.Version != CurrentVersion {
+				t.Fatalf("Version = %d after migrating, want %d", v.config.Version, CurrentVersion)
+			}
+			if err = v.Unlock(testPassword); err != nil {
+				t.Fatal(err)
+			}
+			if _, got, err := v.Get("bank"); err != nil || got != "secret" {
+				t.Fatalf("Get(bank) = %q, %v after migrating", got, err)
+			}
+		})
+	}
+}
+
+func TestNewerEntryIsRefused(t *testing.T) {
+	v, s := newTestVault(t)
+	if err := v.Add(site("bank"), "secret"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.ReadEntry("bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[0] = entryVersion + 1
+	if err = s.WriteEntry("bank", data); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = v.Get("bank"); !errors.Is(err, ErrNewerVersion) {
+		t.Fatalf("Get() of a newer password file = %v, want ErrNewerVersion", err)
+	}
+}
+
+func writeIndexVersion(t *testing.T, s Storage, version int) {
+	t.Helper()
+	data, err := s.ReadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index map[string]interface{}
+	if err = json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	index["Version"] = version
+	if data, err = json.Marshal(index); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.WriteIndex(data); err != nil {
+		t.Fatal(err)
+	}
+}
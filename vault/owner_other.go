@@ -0,0 +1,10 @@
+//go:build windows || plan9 || js
+
+package vault
+
+import "os"
+
+// Ownership is not exposed through os.FileInfo on these platforms
+func ownedByCurrentUser(info os.FileInfo) bool {
+	return true
+}
@@ -0,0 +1,16 @@
+//go:build !windows && !plan9 && !js
+
+package vault
+
+import (
+	"os"
+	"syscall"
+)
+
+func ownedByCurrentUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true
+	}
+	return int(stat.Uid) == os.Getuid()
+}
@@ -0,0 +1,100 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrInsecurePermissions is returned when files of the vault can be accessed by other users
+var ErrInsecurePermissions = errors.New("vault is accessible by other users")
+
+// PermissionChecker is implemented by storages that can be exposed to other users,
+// Open calls CheckPermissions before reading anything
+type PermissionChecker interface {
+	// CheckPermissions returns a warning for every insecure mode it repaired
+	CheckPermissions() ([]string, error)
+}
+
+// CheckPermissions makes sure that the pass dir, its config, index and vault folder belong to
+// the current user and are not accessible by group or others. Folders must be 0700 and files
+// 0600 at most. The pass dir itself may be a symlink, symlinks inside it are refused.
+// Backups and the journal are not checked, they are written with safe modes by mypass.
+// With FixPermissions set the modes are repaired and a warning returned for each, ownership and symlinks always fail.
+// The warnings are returned along with an error as well, the modes repaired before it stay repaired.
+func (f *FileStorage) CheckPermissions() ([]string, error) {
+	// the mode bits do not reflect the ACLs used on Windows
+	if runtime.GOOS == "windows" {
+		return nil, nil
+	}
+	var problems, warnings []string
+	check := func(path string, info os.FileInfo) error {
+		if info.Mode()&os.ModeSymlink != 0 {
+			problems = append(problems, fmt.Sprintf("%s is a symlink", path))
+			return nil
+		}
+		if !ownedByCurrentUser(info) {
+			problems = append(problems, fmt.Sprintf("%s is owned by another user", path))
+			return nil
+		}
+		if info.Mode().Perm()&0077 == 0 {
+			return nil
+		}
+		if !f.FixPermissions {
+			problems = append(problems, fmt.Sprintf("%s has mode %04o", path, info.Mode().Perm()))
+			return nil
+		}
+		mode := os.FileMode(0600)
+		if info.IsDir() {
+			mode = 0700
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			return err
+		}
+		warnings = append(warnings, fmt.Sprintf("%s had mode %04o, changed to %04o", path, info.Mode().Perm(), mode))
+		return nil
+	}
+
+	root, err := filepath.EvalSymlinks(f.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not check permissions: %w", err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return warnings, fmt.Errorf("could not check permissions: %w", err)
+	}
+	if err = check(f.dir, info); err != nil {
+		return warnings, fmt.Errorf("could not check permissions: %w", err)
+	}
+	for _, path := range []string{f.configFile(), f.siteFile()} {
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err == nil {
+			err = check(path, info)
+		}
+		if err != nil {
+			return warnings, fmt.Errorf("could not check permissions: %w", err)
+		}
+	}
+	err = filepath.Walk(f.vaultFolder(), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return check(path, info)
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return warnings, fmt.Errorf("could not check permissions: %w", err)
+	}
+	if len(problems) > 0 {
+		return warnings, fmt.Errorf("%w: %s", ErrInsecurePermissions, strings.Join(problems, ", "))
+	}
+	return warnings, nil
+}
@@ -0,0 +1,104 @@
+package vault
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/jeremyphua/mypass/io"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions are not checked on Windows")
+	}
+	tests := []struct {
+		name    string
+		change  func(t *testing.T, dir string)
+		fix     bool
+		wantErr bool
+		// number of modes repaired
+		warnings int
+	}{
+		{name: "new vault", change: func(t *testing.T, dir string) {}},
+		{name: "readable backups are not checked", change: func(t *testing.T, dir string) {
+			chmod(t, filepath.Join(dir, BackupFolderName), 0755)
+		}},
+		{name: "readable config", change: func(t *testing.T, dir string) {
+			chmod(t, filepath.Join(dir, io.ConfigFileName), 0644)
+		}, wantErr: true},
+		{name: "readable index", change: func(t *testing.T, dir string) {
+			chmod(t, filepath.Join(dir, io.SiteFileName), 0644)
+		}, wantErr: true},
+		{name: "readable group", change: func(t *testing.T, dir string) {
+			chmod(t, filepath.Join(dir, io.VaultFolderName, "work"), 0755)
+		}, wantErr: true},
+		{name: "readable entry", change: func(t *testing.T, dir string) {
+			chmod(t, filepath.Join(dir, io.VaultFolderName, "work", "mail"), 0644)
+		}, wantErr: true},
+		{name: "readable entry is repaired", change: func(t *testing.T, dir string) {
+			chmod(t, filepath.Join(dir, io.VaultFolderName, "work", "mail"), 0644)
+		}, fix: true, warnings: 1},
+		{name: "symlink in the vault folder", change: func(t *testing.T, dir string) {
+			if err := os.Symlink(os.DevNull, filepath.Join(dir, io.VaultFolderName, "work", "link")); err != nil {
+				t.Fatal(err)
+			}
+		}, fix: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newFileVault(t)
+			tt.change(t, dir)
+			// the pass dir is opened through a symlink like a ~/.mypass kept on another disk
+			link := filepath.Join(t.TempDir(), "mypass")
+			if err := os.Symlink(dir, link); err != nil {
+				t.Fatal(err)
+			}
+			f := NewFileStorage(link, DefaultFileOptions())
+			f.FixPermissions = tt.fix
+			warnings, err := f.CheckPermissions()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInsecurePermissions) {
+					t.Fatalf("CheckPermissions() = %v, want %v", err, ErrInsecurePermissions)
+				}
+				return
+			}
+			if err != nil || len(warnings) != tt.warnings {
+				t.Fatalf("CheckPermissions() = %v, %v, want %d warnings", warnings, err, tt.warnings)
+			}
+			if warnings, err = f.CheckPermissions(); err != nil || len(warnings) > 0 {
+				t.Fatalf("second CheckPermissions() = %v, %v", warnings, err)
+			}
+		})
+	}
+}
+
+// newFileVault creates a vault with the site work/mail and a backup in a temporary dir
+func newFileVault(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "mypass")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	s := NewFileStorage(dir, DefaultFileOptions())
+	v, err := Create(s, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = v.Put(site("work/mail"), "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Backup("test"); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func chmod(t *testing.T, path string, mode os.FileMode) {
+	t.Helper()
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+}
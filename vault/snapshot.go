@@ -0,0 +1,332 @@
+package vault
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	goio "io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jeremyphua/mypass/io"
+)
+
+// SnapshotFormat identifies the manifest of a backup tarball
+const SnapshotFormat = "mypass-backup"
+
+const (
+	snapshotVersion  = 1
+	manifestFileName = "MANIFEST.json"
+)
+
+// ErrCorruptSnapshot is returned when a backup tarball fails its integrity check
+var ErrCorruptSnapshot = errors.New("backup is corrupted")
+
+// Manifest is the first file of a backup tarball, listing the SHA-256 of every other file
+type Manifest struct {
+	Format       string
+	Version      int
+	Created      time.Time
+	VaultVersion int
+	// Sites is the number of sites in sites.json, 0 when the index is encrypted
+	Sites int
+	// SitesUnknown is set when sites.json could not be read to count its sites
+	SitesUnknown   bool `json:",omitempty"`
+	EncryptedIndex bool
+	// Files maps the path of every file in the tarball to its hex SHA-256
+	Files map[string]string
+}
+
+// WriteSnapshot writes masterpass, sites.json and every password file of s to w as a
+// gzipped tarball, holding the storage lock while reading them. Nothing is decrypted.
+func WriteSnapshot(s Storage, w goio.Writer) (*Manifest, error) {
+	release, err := s.Acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	files := map[string][]byte{}
+	if files[io.ConfigFileName], err = s.ReadConfig(); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, io.ErrVaultNotInitialized
+		}
+		return nil, fmt.Errorf("could not read config file: %w", err)
+	}
+	if files[io.SiteFileName], err = s.ReadIndex(); err != nil {
+		return nil, fmt.Errorf("could not read site file: %w", err)
+	}
+	names, err := s.ListEntries()
+	if err != nil {
+		return nil, fmt.Errorf("could not list the password files: %w", err)
+	}
+	for _, name := range names {
+		if files[path.Join(io.VaultFolderName, name)], err = s.ReadEntry(name); err != nil {
+			return nil, fmt.Errorf("could not read password file %s: %w", name, err)
+		}
+	}
+	var config io.ConfigFile
+	if err = json.Unmarshal(files[io.ConfigFileName], &config); err != nil {
+		return nil, fmt.Errorf("could not unmarshal config file: %w", err)
+	}
+
+	m := &Manifest{
+		Format:         SnapshotFormat,
+		Version:        snapshotVersion,
+		Created:        time.Now().UTC(),
+		VaultVersion:   config.Version,
+		EncryptedIndex: config.EncryptedIndex,
+		Files:          map[string]string{},
+	}
+	// password files missing from sites.json are backed up but not counted,
+	// the sites of an encrypted index can't be counted without the master password.
+	// A damaged sites.json is backed up as it is, only its sites are not counted.
+	var index indexFile
+	if json.Unmarshal(files[io.SiteFileName], &index) == nil {
+		m.Sites = len(index.Sites)
+	} else {
+		m.SitesUnknown = true
+	}
+	for name, data := range files {
+		m.Files[name] = checksum(data)
+	}
+	manifest, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal manifest: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	// the manifest goes first so that restoring can check every file as it comes
+	paths := make([]string, 0, len(files))
+	for name := range files {
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+	if err = writeTarFile(tw, manifestFileName, manifest, m.Created); err != nil {
+		return nil, err
+	}
+	for _, name := range paths {
+		if err = writeTarFile(tw, name, files[name], m.Created); err != nil {
+			return nil, err
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return nil, fmt.Errorf("could not write backup: %w", err)
+	}
+	if err = gz.Close(); err != nil {
+		return nil, fmt.Errorf("could not write backup: %w", err)
+	}
+	return m, nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	h := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(h); err != nil {
+		return fmt.Errorf("could not write backup: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("could not write backup: %w", err)
+	}
+	return nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Snapshot is a backup tarball read and checked by ReadSnapshot
+type Snapshot struct {
+	Manifest Manifest
+	config   []byte
+	index    []byte
+	entries  map[string][]byte
+}
+
+// ReadSnapshot reads a backup tarball and checks it against its manifest:
+// every file must be listed with a matching checksum, and no listed file may be missing.
+// Vaults of a newer format than this mypass reads are refused.
+func ReadSnapshot(r goio.Reader) (*Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCorruptSnapshot, err.Error())
+	}
+	tr := tar.NewReader(gz)
+
+	snap := &Snapshot{entries: map[string][]byte{}}
+	seen := map[string]bool{}
+	for first := true; ; first = false {
+		h, err := tr.Next()
+		if errors.Is(err, goio.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrCorruptSnapshot, err.Error())
+		}
+		data, err := goio.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrCorruptSnapshot, err.Error())
+		}
+		if first {
+			if h.Name != manifestFileName {
+				return nil, fmt.Errorf("%w: not a mypass backup", ErrCorruptSnapshot)
+			}
+			if err = json.Unmarshal(data, &snap.Manifest); err != nil || snap.Manifest.Format != SnapshotFormat {
+				return nil, fmt.Errorf("%w: not a mypass backup", ErrCorruptSnapshot)
+			}
+			if snap.Manifest.Version > snapshotVersion {
+				return nil, fmt.Errorf("backup format version %d was written by a newer version of mypass", snap.Manifest.Version)
+			}
+			continue
+		}
+		sum, ok := snap.Manifest.Files[h.Name]
+		if !ok || seen[h.Name] {
+			return nil, fmt.Errorf("%w: unexpected file %s", ErrCorruptSnapshot, h.Name)
+		}
+		if checksum(data) != sum {
+			return nil, fmt.Errorf("%w: checksum mismatch for %s", ErrCorruptSnapshot, h.Name)
+		}
+		seen[h.Name] = true
+		switch {
+		case h.Name == io.ConfigFileName:
+			snap.config = data
+		case h.Name == io.SiteFileName:
+			snap.index = data
+		case strings.HasPrefix(h.Name, io.VaultFolderName+"/"):
+			name := strings.TrimPrefix(h.Name, io.VaultFolderName+"/")
+			if err = io.CheckStoredSiteName(name); err != nil {
+				return nil, fmt.Errorf("%w: invalid password file %s", ErrCorruptSnapshot, h.Name)
+			}
+			snap.entries[name] = data
+		default:
+			return nil, fmt.Errorf("%w: unexpected file %s", ErrCorruptSnapshot, h.Name)
+		}
+	}
+	for name := range snap.Manifest.Files {
+		if !seen[name] {
+			return nil, fmt.Errorf("%w: %s is missing", ErrCorruptSnapshot, name)
+		}
+	}
+	if snap.config == nil || snap.index == nil {
+		return nil, fmt.Errorf("%w: masterpass or sites.json is missing", ErrCorruptSnapshot)
+	}
+	var config io.ConfigFile
+	if err = json.Unmarshal(snap.config, &config); err != nil {
+		return nil, fmt.Errorf("%w: could not unmarshal config file: %s", ErrCorruptSnapshot, err.Error())
+	}
+	if err = checkVersion(config.Version, CurrentVersion); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Restore writes the snapshot to s. An existing vault is only replaced when force is set,
+// in a single Storage.Replace after a rolling backup of it. An empty storage is prepared like by Create
+// and gets masterpass written last, so that an interrupted restore does not leave a vault behind.
+func (snap *Snapshot) Restore(s Storage, force bool) error {
+	release, err := s.Acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = s.ReadConfig()
+	switch {
+	case err == nil && !force:
+		return fmt.Errorf("%w, restoring over it needs --force", io.ErrVaultAlreadyInitialized)
+	case err == nil:
+		if b, ok := s.(AutoBackuper); ok {
+			if err = b.AutoBackup("restore"); err != nil {
+				return fmt.Errorf("could not back up the vault before restoring over it: %w", err)
+			}
+		}
+		if err = s.Replace(snap.config, snap.index, snap.entries); err != nil {
+			return fmt.Errorf("could not swap in the restored vault, the previous vault was kept: %w", err)
+		}
+		return nil
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+
+	// a vault without sites still needs its vault folder
+	if err = initStorage(s); err != nil {
+		return err
+	}
+	for name, data := range snap.entries {
+		if err = s.WriteEntry(name, data); err != nil {
+			return fmt.Errorf("could not write password file %s: %w", name, err)
+		}
+	}
+	if err = s.WriteIndex(snap.index); err != nil {
+		return fmt.Errorf("could not write site file: %w", err)
+	}
+	if err = s.WriteConfig(snap.config); err != nil {
+		return fmt.Errorf("could not write config file: %w", err)
+	}
+	return nil
+}
+
+// AutoBackupFolderName is the folder of the backups folder holding the rolling backups
+const AutoBackupFolderName = "auto"
+
+// AutoBackuper is implemented by storages keeping rolling backups,
+// taken by the vault before deleting, renaming or re-encrypting sites
+type AutoBackuper interface {
+	AutoBackup(reason string) error
+}
+
+// AutoBackup writes a backup tarball to backups/auto in the pass dir and removes
+// the oldest ones beyond AutoBackups. It does nothing when AutoBackups is 0.
+func (f *FileStorage) AutoBackup(reason string) error {
+	if f.AutoBackups <= 0 {
+		return nil
+	}
+	dir := filepath.Join(f.dir, BackupFolderName, AutoBackupFolderName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("could not create backup folder: %w", err)
+	}
+	var buf bytes.Buffer
+	if _, err := WriteSnapshot(f, &buf); err != nil {
+		return err
+	}
+	// the time sorts the backups, the reason tells them apart
+	name := fmt.Sprintf("%s-%s.tar.gz", time.Now().UTC().Format("20060102-150405.000000"), reason)
+	if err := io.WriteFileAtomic(filepath.Join(dir, name), buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("could not write backup: %w", err)
+	}
+
+	backups, err := filepath.Glob(filepath.Join(dir, "*.tar.gz"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(backups)
+	for len(backups) > f.AutoBackups {
+		if err = os.Remove(backups[0]); err != nil {
+			return fmt.Errorf("could not remove old backup: %w", err)
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// Take a rolling backup before an operation that deletes or rewrites sites, if the storage keeps them
+func (v *Vault) autoBackup(reason string) error {
+	b, ok := v.storage.(AutoBackuper)
+	if !ok {
+		return nil
+	}
+	if err := b.AutoBackup(reason); err != nil {
+		return fmt.Errorf("could not back up the vault before %s: %w", reason, err)
+	}
+	return nil
+}
@@ -0,0 +1,333 @@
+package vault
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	goio "io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/jeremyphua/mypass/io"
+)
+
+// tarFile is a file of a backup tarball, in order
+type tarFile struct {
+	name string
+	data []byte
+}
+
+// Write a vault with two sites to a backup tarball
+func newSnapshot(t *testing.T, encrypted bool) []byte {
+	t.Helper()
+	v, s := newTestVault(t)
+	if _, err := v.EncryptIndex(encrypted, false); err != nil {
+		t.Fatal(err)
+	}
+	for name, password := range map[string]string{"web/github": "pw1", "bank": "pw2"} {
+		if err := v.Add(site(name), password); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	m, err := WriteSnapshot(s, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the sites of an encrypted index are not counted
+	sites := 2
+	if encrypted {
+		sites = 0
+	}
+	if m.Sites != sites || m.EncryptedIndex != encrypted || m.VaultVersion != CurrentVersion || len(m.Files) != 4 {
+		t.Fatalf("manifest has %d sites, encrypted index %v, version %d and %d files", m.Sites, m.EncryptedIndex, m.VaultVersion, len(m.Files))
+	}
+	return buf.Bytes()
+}
+
+func TestSnapshotCountsIndexedSites(t *testing.T) {
+	v, s := newTestVault(t)
+	if err := v.Add(site("bank"), "pw"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.ReadEntry("bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.WriteEntry("orphan", data); err != nil {
+		t.Fatal(err)
+	}
+	m, err := WriteSnapshot(s, goio.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the orphan is backed up but is no site
+	if m.Sites != 1 || len(m.Files) != 4 {
+		t.Fatalf("manifest has %d sites and %d files, want 1 site and 4 files", m.Sites, len(m.Files))
+	}
+}
+
+// A damaged sites.json is backed up as it is, and the vault can still be restored over
+func TestSnapshotOfDamagedIndex(t *testing.T) {
+	dir := newFileVault(t)
+	s := NewFileStorage(dir, DefaultFileOptions())
+	if err := s.WriteIndex([]byte("[not json")); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	m, err := WriteSnapshot(s, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.SitesUnknown || m.Sites != 0 || len(m.Files) != 3 {
+		t.Fatalf("manifest has %d sites, unknown %v and %d files", m.Sites, m.SitesUnknown, len(m.Files))
+	}
+
+	snap, err := ReadSnapshot(bytes.NewReader(newSnapshot(t, false)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = snap.Restore(s, true); err != nil {
+		t.Fatal(err)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(dir, BackupFolderName, AutoBackupFolderName, "*")); len(backups) != 1 {
+		t.Errorf("backups after the restore: %v, want the one taken before it", backups)
+	}
+	if _, got, err := openUnlocked(t, s, testPassword).Get("bank"); err != nil || got != "pw2" {
+		t.Errorf("Get(bank) = %q, %v after restoring over a damaged vault", got, err)
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		snap, err := ReadSnapshot(bytes.NewReader(newSnapshot(t, encrypted)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := NewMemStorage()
+		if err = snap.Restore(s, false); err != nil {
+			t.Fatal(err)
+		}
+		v := openUnlocked(t, s, testPassword)
+		for name, want := range map[string]string{"web/github": "pw1", "bank": "pw2"} {
+			if _, got, err := v.Get(name); err != nil || got != want {
+				t.Errorf("Get(%q) = %q, %v after restore, want %q", name, got, err, want)
+			}
+		}
+		if v.IndexEncrypted() != encrypted {
+			t.Errorf("IndexEncrypted() = %v after restore, want %v", v.IndexEncrypted(), encrypted)
+		}
+	}
+}
+
+func TestSnapshotRestoreOverVault(t *testing.T) {
+	snap, err := ReadSnapshot(bytes.NewReader(newSnapshot(t, false)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, s := newTestVault(t)
+	if err = v.Add(site("other"), "pw3"); err != nil {
+		t.Fatal(err)
+	}
+	if err = snap.Restore(s, false); !errors.Is(err, io.ErrVaultAlreadyInitialized) {
+		t.Fatalf("Restore() without force = %v, want ErrVaultAlreadyInitialized", err)
+	}
+	if err = snap.Restore(s, true); err != nil {
+		t.Fatal(err)
+	}
+	v = openUnlocked(t, s, testPassword)
+	if _, err = v.Site("other"); !errors.Is(err, io.ErrSiteNotFound) {
+		t.Errorf("Site(other) = %v after a forced restore, want ErrSiteNotFound", err)
+	}
+	if _, got, err := v.Get("bank"); err != nil || got != "pw2" {
+		t.Errorf("Get(bank) = %q, %v after a forced restore", got, err)
+	}
+}
+
+func TestSnapshotRoundTripWithoutSites(t *testing.T) {
+	from := filepath.Join(t.TempDir(), "from")
+	if err := os.Mkdir(from, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Create(NewFileStorage(from, DefaultFileOptions()), testPassword); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := WriteSnapshot(NewFileStorage(from, DefaultFileOptions()), &buf); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := ReadSnapshot(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "to")
+	if err = os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	checkVaultFolder := func(when string) {
+		t.Helper()
+		info, err := os.Stat(filepath.Join(dir, io.VaultFolderName))
+		if err != nil || !info.IsDir() {
+			t.Fatalf("vault folder %s: %v", when, err)
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm() != 0700 {
+			t.Errorf("vault folder has mode %v %s, want 0700", info.Mode().Perm(), when)
+		}
+	}
+	if err = snap.Restore(NewFileStorage(dir, DefaultFileOptions()), false); err != nil {
+		t.Fatal(err)
+	}
+	checkVaultFolder("after restoring")
+
+	// a vault left without its folder by an earlier restore can still be restored over
+	if err = os.Remove(filepath.Join(dir, io.VaultFolderName)); err != nil {
+		t.Fatal(err)
+	}
+	if err = snap.Restore(NewFileStorage(dir, DefaultFileOptions()), true); err != nil {
+		t.Fatal(err)
+	}
+	checkVaultFolder("after restoring over the vault")
+	v := openUnlocked(t, NewFileStorage(dir, DefaultFileOptions()), testPassword)
+	if err = v.Add(site("bank"), "pw"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadSnapshotRejectsTampering(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(files []tarFile) []tarFile
+		wantErr error
+	}{
+		{"untouched", func(files []tarFile) []tarFile { return files }, nil},
+		{"changed entry", func(files []tarFile) []tarFile {
+			files[len(files)-1].data[0] ^= 1
+			return files
+		}, ErrCorruptSnapshot},
+		{"changed index", func(files []tarFile) []tarFile {
+			return setFile(files, io.SiteFileName, []byte("[]"))
+		}, ErrCorruptSnapshot},
+		{"missing entry", func(files []tarFile) []tarFile {
+			return files[:len(files)-1]
+		}, ErrCorruptSnapshot},
+		{"extra file", func(files []tarFile) []tarFile {
+			return append(files, tarFile{"vault/extra", []byte("x")})
+		}, ErrCorruptSnapshot},
+		{"duplicate file", func(files []tarFile) []tarFile {
+			return append(files, files[len(files)-1])
+		}, ErrCorruptSnapshot},
+		{"manifest not first", func(files []tarFile) []tarFile {
+			files[0], files[1] = files[1], files[0]
+			return files
+		}, ErrCorruptSnapshot},
+		{"entry outside the vault folder", func(files []tarFile) []tarFile {
+			return addListed(files, "vault/../escape", []byte("x"))
+		}, ErrCorruptSnapshot},
+		{"newer vault", func(files []tarFile) []tarFile {
+			var config map[string]interface{}
+			if err := json.Unmarshal(fileData(files, io.ConfigFileName), &config); err != nil {
+				panic(err)
+			}
+			config["Version"] = CurrentVersion + 1
+			data, _ := json.Marshal(config)
+			return relist(setFile(files, io.ConfigFileName, data))
+		}, ErrNewerVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := writeTar(t, tt.tamper(readTar(t, newSnapshot(t, false))))
+			if _, err := ReadSnapshot(bytes.NewReader(data)); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadSnapshot() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := ReadSnapshot(bytes.NewReader([]byte("not gzip"))); !errors.Is(err, ErrCorruptSnapshot) {
+		t.Fatalf("ReadSnapshot() of garbage = %v, want ErrCorruptSnapshot", err)
+	}
+}
+
+func readTar(t *testing.T, data []byte) []tarFile {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []tarFile
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, goio.EOF) {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := goio.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, tarFile{h.Name, data})
+	}
+}
+
+func writeTar(t *testing.T, files []tarFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		if err := writeTarFile(tw, f.name, f.data, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func fileData(files []tarFile, name string) []byte {
+	for _, f := range files {
+		if f.name == name {
+			return f.data
+		}
+	}
+	return nil
+}
+
+func setFile(files []tarFile, name string, data []byte) []tarFile {
+	for i := range files {
+		if files[i].name == name {
+			files[i].data = data
+		}
+	}
+	return files
+}
+
+// Add a file along with its checksum in the manifest
+func addListed(files []tarFile, name string, data []byte) []tarFile {
+	return relist(append(files, tarFile{name, data}))
+}
+
+// Rewrite the manifest with the checksums of files, like a forged backup would
+func relist(files []tarFile) []tarFile {
+	var m Manifest
+	if err := json.Unmarshal(files[0].data, &m); err != nil {
+		panic(err)
+	}
+	m.Files = map[string]string{}
+	for _, f := range files[1:] {
+		m.Files[f.name] = checksum(f.data)
+	}
+	files[0].data, _ = json.Marshal(m)
+	return files
+}
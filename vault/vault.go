@@ -0,0 +1,734 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/pc"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// ErrLocked is returned when an operation needs the master private key but the vault was not unlocked
+var ErrLocked = errors.New("vault has not been unlocked")
+
+// ErrVaultChanged is returned when another process changed the master password or rotated
+// the master key pair after this vault was unlocked, the operation has to be run again
+var ErrVaultChanged = errors.New("vault was changed by another mypass process since it was unlocked, retry")
+
+// Storage persists the three parts of a vault: the masterpass config, the sites.json index
+// and the sealed password of every site. Reading something that does not exist returns an
+// error wrapping os.ErrNotExist.
+type Storage interface {
+	ReadConfig() ([]byte, error)
+	WriteConfig(data []byte) error
+	ReadIndex() ([]byte, error)
+	WriteIndex(data []byte) error
+	ReadEntry(name string) ([]byte, error)
+	WriteEntry(name string, data []byte) error
+	RemoveEntry(name string) error
+	RenameEntry(oldName, newName string) error
+	// ListEntries returns the names of every stored entry, including those missing from the index
+	ListEntries() ([]string, error)
+	// Replace swaps in a whole new config, index and set of entries at once,
+	// leaving the previous contents in place if it fails
+	Replace(config, index []byte, entries map[string][]byte) error
+	// Atomic runs fn, which may change the index and the given entries.
+	// If fn fails they are all restored to what they were before.
+	Atomic(entries []string, fn func() error) error
+	// Acquire takes the lock held by every operation that changes the vault,
+	// including from other processes. The returned func releases it.
+	Acquire() (release func(), err error)
+}
+
+// Recoverer is implemented by storages that can be left halfway through an operation,
+// Open calls Recover to roll such an operation back before using the storage
+type Recoverer interface {
+	Recover() error
+}
+
+// Initializer is implemented by storages that have to be prepared before a new vault is written
+// to them, Create and restoring a backup call Init first
+type Initializer interface {
+	Init() error
+}
+
+// TryAcquirer is implemented by storages that can tell at once whether the lock is free
+type TryAcquirer interface {
+	// TryAcquire takes the lock taken by Acquire, failing with ErrInUse without waiting if it is held
+	TryAcquire() (release func(), err error)
+}
+
+// Vault is a password vault stored in a Storage.
+// Listing and adding sites only needs the master public key, unless the index is encrypted.
+// Reading passwords needs the vault to be unlocked.
+type Vault struct {
+	storage Storage
+	config  io.ConfigFile
+
+	// only set while unlocked
+	passKey       []byte
+	masterPrivKey *[32]byte
+
+	// what Open repaired or migrated, for the caller to report
+	notices []string
+}
+
+// Open reads the config of an existing vault. The vault starts locked.
+// Storages exposed to other users are checked for insecure permissions first,
+// vaults written in an older format are migrated to CurrentVersion.
+func Open(s Storage) (*Vault, error) {
+	v := &Vault{storage: s}
+	if c, ok := s.(PermissionChecker); ok {
+		warnings, err := c.CheckPermissions()
+		if err != nil {
+			if len(warnings) > 0 {
+				return nil, fmt.Errorf("%w, after repairing: %s", err, strings.Join(warnings, ", "))
+			}
+			return nil, err
+		}
+		for _, w := range warnings {
+			v.notices = append(v.notices, "Warning: "+w)
+		}
+	}
+	if r, ok := s.(Recoverer); ok {
+		if err := r.Recover(); err != nil {
+			return nil, fmt.Errorf("could not roll back interrupted operation: %w", err)
+		}
+	}
+	if err := v.readConfig(); err != nil {
+		return nil, err
+	}
+	if v.config.Version < CurrentVersion {
+		if err := v.migrate(); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// Notices returns the insecure modes repaired and the migrations done by Open, to be shown to the user
+func (v *Vault) Notices() []string {
+	return v.notices
+}
+
+// Create initializes a new vault protected by the master password and returns it unlocked.
+// An existing index is kept so that an interrupted init can be run again.
+func Create(s Storage, password string) (*Vault, error) {
+	release, err := s.Acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if _, err := s.ReadConfig(); err == nil {
+		return nil, io.ErrVaultAlreadyInitialized
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not read config file: %w", err)
+	}
+	if err = initStorage(s); err != nil {
+		return nil, err
+	}
+
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate master key pair: %w", err)
+	}
+	salt, err := pc.NewSalt()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate salt: %w", err)
+	}
+	params := pc.DefaultArgon2Params()
+	passKey, err := pc.DeriveKey(password, salt, &params)
+	if err != nil {
+		return nil, err
+	}
+	v := &Vault{
+		storage: s,
+		config: io.ConfigFile{
+			Version:      CurrentVersion,
+			Salt:         salt,
+			Argon2:       &params,
+			MasterPubKey: *pub,
+		},
+		passKey:       passKey,
+		masterPrivKey: priv,
+	}
+	// Encrypt master private key with a key derived from the master password
+	if v.config.MasterPrivKeySealed, err = pc.SecretboxSeal(v.passKey, priv[:]); err != nil {
+		return nil, fmt.Errorf("could not encrypt master key: %w", err)
+	}
+
+	data, err := s.ReadIndex()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not read site file: %w", err)
+	}
+	// the index written by init, or kept from an earlier vault, is a bare list of sites without a version
+	var sites io.SiteFile
+	if err != nil || json.Unmarshal(data, &sites) == nil {
+		if err = v.writeSites(sites); err != nil {
+			return nil, err
+		}
+	}
+	if err = v.writeConfig(v.config); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Prepare s for a new vault, if it needs to be
+func initStorage(s Storage) error {
+	i, ok := s.(Initializer)
+	if !ok {
+		return nil
+	}
+	if err := i.Init(); err != nil {
+		return fmt.Errorf("could not create vault: %w", err)
+	}
+	return nil
+}
+
+// Unlock opens the master private key with the master password.
+// masterpass files written before the salt was introduced are migrated on the first unlock.
+func (v *Vault) Unlock(password string) error {
+	if v.config.IsLegacy() {
+		return v.migrateLegacyConfig(password)
+	}
+
+	passKey, err := pc.DeriveKey(password, v.config.Salt, v.config.Argon2)
+	if err != nil {
+		return err
+	}
+	masterPrivKeySlice, ok := pc.SecretboxOpen(passKey, v.config.MasterPrivKeySealed)
+	if !ok {
+		return pc.ErrWrongMasterPassword
+	}
+	var masterPrivKey [32]byte
+	copy(masterPrivKey[:], masterPrivKeySlice)
+	v.passKey = passKey
+	v.masterPrivKey = &masterPrivKey
+	return nil
+}
+
+// Lock forgets the master private key and the key derived from the master password
+func (v *Vault) Lock() {
+	if v.masterPrivKey != nil {
+		*v.masterPrivKey = [32]byte{}
+	}
+	for i := range v.passKey {
+		v.passKey[i] = 0
+	}
+	v.masterPrivKey = nil
+	v.passKey = nil
+}
+
+// IsLocked reports whether the vault needs to be unlocked before reading passwords
+func (v *Vault) IsLocked() bool {
+	return v.masterPrivKey == nil
+}
+
+// List returns every site in the vault
+func (v *Vault) List() (io.SiteFile, error) {
+	return v.readSites()
+}
+
+// Entries returns the names of the sealed passwords in the storage, which may differ from List
+// if the index and the storage went out of sync
+func (v *Vault) Entries() ([]string, error) {
+	return v.storage.ListEntries()
+}
+
+// Site returns the information of a single site without its password
+func (v *Vault) Site(name string) (io.SiteInfo, error) {
+	sites, err := v.readSites()
+	if err != nil {
+		return io.SiteInfo{}, err
+	}
+	index, err := findSite(sites, name)
+	if err != nil {
+		return io.SiteInfo{}, err
+	}
+	return sites[index], nil
+}
+
+// Index of an existing site. Its name is not validated, sites stored before site names were
+// validated keep working until renamed. Surrounding whitespace is ignored like NormalizeSiteName does.
+func findSite(sites io.SiteFile, name string) (int, error) {
+	index, err := sites.Find(name)
+	if err != nil && strings.TrimSpace(name) != name {
+		return sites.Find(strings.TrimSpace(name))
+	}
+	return index, err
+}
+
+// Name of the site called name as stored, or name validated as the name of a new site
+func (v *Vault) siteName(name string) (string, error) {
+	if si, err := v.Site(name); err == nil {
+		return si.Name, nil
+	}
+	return io.NormalizeSiteName(name)
+}
+
+// Get returns the information and decrypted password of a site
+func (v *Vault) Get(name string) (io.SiteInfo, string, error) {
+	if v.IsLocked() {
+		return io.SiteInfo{}, "", ErrLocked
+	}
+	si, err := v.Site(name)
+	if err != nil {
+		return si, "", err
+	}
+	encrypted, err := v.readEntry(si.Name)
+	if err != nil {
+		return si, "", err
+	}
+	password, ok := pc.BoxOpen(encrypted, &si.PubKey, v.masterPrivKey)
+	if !ok {
+		return si, "", fmt.Errorf("error decrypting password of %s", si.Name)
+	}
+	return si, string(password), nil
+}
+
+// Add stores a new site, failing if a site with the same name exists
+func (v *Vault) Add(site io.SiteInfo, password string) (err error) {
+	if site.Name, err = io.NormalizeSiteName(site.Name); err != nil {
+		return err
+	}
+	entries, err := v.entryNames(site.Name)
+	if err != nil {
+		return err
+	}
+	return v.atomic(entries, func() error {
+		sites, err := v.readSites()
+		if err != nil {
+			return err
+		}
+		if _, err = sites.Find(site.Name); err == nil {
+			return fmt.Errorf("%w: %s", io.ErrDuplicateSite, site.Name)
+		}
+		return v.put(sites, -1, site, password)
+	})
+}
+
+// Put stores the password of a site, replacing the current one if the site exists
+func (v *Vault) Put(site io.SiteInfo, password string) (err error) {
+	if site.Name, err = v.siteName(site.Name); err != nil {
+		return err
+	}
+	entries, err := v.entryNames(site.Name)
+	if err != nil {
+		return err
+	}
+	return v.atomic(entries, func() error {
+		sites, err := v.readSites()
+		if err != nil {
+			return err
+		}
+		index, _ := sites.Find(site.Name)
+		return v.put(sites, index, site, password)
+	})
+}
+
+// Seal the password for site and store it at index of sites, or append it if index is -1.
+// The password it replaces is added to the history of the site.
+func (v *Vault) put(sites io.SiteFile, index int, site io.SiteInfo, password string) (err error) {
+	now := time.Now().UTC()
+	site.History = nil
+	site.Created, site.PasswordChanged = now, now
+	if index >= 0 {
+		// keep the replaced password so that the change can be undone
+		if site.History, err = v.pushHistory(sites[index]); err != nil {
+			return err
+		}
+		site.Created, site.LastShown = sites[index].Created, sites[index].LastShown
+	}
+	site, passSealed, err := pc.ReEncrypt(site, password, &v.config.MasterPubKey)
+	if err != nil {
+		return err
+	}
+	entry, err := v.entryName(site.Name)
+	if err != nil {
+		return err
+	}
+	// the password is written first so that the index never points to a missing file
+	if err = v.storage.WriteEntry(entry, entryData(passSealed)); err != nil {
+		return fmt.Errorf("could not save password of %s: %w", site.Name, err)
+	}
+	if index < 0 {
+		sites = append(sites, site)
+	} else {
+		sites[index] = site
+	}
+	return v.writeSites(sites)
+}
+
+// Update replaces the information of an existing site, keeping its password, history and timestamps
+func (v *Vault) Update(site io.SiteInfo) (err error) {
+	existing, err := v.Site(site.Name)
+	if err != nil {
+		return err
+	}
+	site.Name = existing.Name
+	return v.atomic(nil, func() error {
+		sites, err := v.readSites()
+		if err != nil {
+			return err
+		}
+		index, err := sites.Find(site.Name)
+		if err != nil {
+			return err
+		}
+		site.PubKey = sites[index].PubKey
+		site.History = sites[index].History
+		site.Created, site.PasswordChanged, site.LastShown = sites[index].Created, sites[index].PasswordChanged, sites[index].LastShown
+		sites[index] = site
+		return v.writeSites(sites)
+	})
+}
+
+// MarkShown records that the password or a field of a site was revealed now.
+// It is best effort: it fails with ErrInUse rather than wait for another process holding
+// the vault, and rewrites sites.json without a journal as only the time can be lost.
+func (v *Vault) MarkShown(name string) (err error) {
+	si, err := v.Site(name)
+	if err != nil {
+		return err
+	}
+	acquire := v.storage.Acquire
+	if t, ok := v.storage.(TryAcquirer); ok {
+		acquire = t.TryAcquire
+	}
+	release, err := acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err = v.refreshConfig(); err != nil {
+		return err
+	}
+	sites, err := v.readSites()
+	if err != nil {
+		return err
+	}
+	index, err := sites.Find(si.Name)
+	if err != nil {
+		return err
+	}
+	sites[index].LastShown = time.Now().UTC()
+	return v.writeSites(sites)
+}
+
+// Delete removes a site and its password
+func (v *Vault) Delete(name string) (err error) {
+	si, err := v.Site(name)
+	if err != nil {
+		return err
+	}
+	name = si.Name
+	entries, err := v.entryNames(name)
+	if err != nil {
+		return err
+	}
+	return v.atomic(entries, func() error {
+		sites, err := v.readSites()
+		if err != nil {
+			return err
+		}
+		index, err := sites.Find(name)
+		if err != nil {
+			return err
+		}
+		if err = v.autoBackup("delete"); err != nil {
+			return err
+		}
+		if err = v.storage.RemoveEntry(entries[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("attempted to remove file but was unable to: %w", err)
+		}
+		return v.writeSites(append(sites[:index], sites[index+1:]...))
+	})
+}
+
+// Rename moves a site and its password to a new name. Only the new name is validated,
+// so that sites stored under names that are no longer valid can be renamed.
+func (v *Vault) Rename(oldName, newName string) (err error) {
+	si, err := v.Site(oldName)
+	if err != nil {
+		return err
+	}
+	oldName = si.Name
+	if newName, err = io.NormalizeSiteName(newName); err != nil {
+		return err
+	}
+	entries, err := v.entryNames(oldName, newName)
+	if err != nil {
+		return err
+	}
+	return v.atomic(entries, func() error {
+		sites, err := v.readSites()
+		if err != nil {
+			return err
+		}
+		index, err := sites.Find(oldName)
+		if err != nil {
+			return err
+		}
+		if _, err = sites.Find(newName); err == nil {
+			return fmt.Errorf("%w: %s", io.ErrDuplicateSite, newName)
+		}
+		if err = v.autoBackup("rename"); err != nil {
+			return err
+		}
+		if err = v.storage.RenameEntry(entries[0], entries[1]); err != nil {
+			return fmt.Errorf("could not rename password file of %s: %w", oldName, err)
+		}
+		sites[index].Name = newName
+		return v.writeSites(sites)
+	})
+}
+
+// ChangePassword re-seals the master private key with a key derived from a new master password.
+// The passwords of the sites are sealed with the master key pair and stay untouched. The backups in
+// the storage still open with the old master password. They are kept unless removeBackups is set:
+// then they are removed once the new password is in place and their paths returned.
+func (v *Vault) ChangePassword(newPassword string, removeBackups bool) ([]string, error) {
+	if v.IsLocked() {
+		return nil, ErrLocked
+	}
+	release, err := v.storage.Acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err = v.refreshConfig(); err != nil {
+		return nil, err
+	}
+
+	c := v.config
+	params := pc.DefaultArgon2Params()
+	salt, sealed, err := pc.SealMasterPrivKey(newPassword, params, v.masterPrivKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not encrypt master key: %w", err)
+	}
+	passKey, err := pc.DeriveKey(newPassword, salt, &params)
+	if err != nil {
+		return nil, err
+	}
+	c.MasterPassKey = nil
+	c.Salt = salt
+	c.Argon2 = &params
+	c.MasterPrivKeySealed = sealed
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal config file: %w", err)
+	}
+	if err = v.storage.WriteConfig(data); err != nil {
+		return nil, fmt.Errorf("could not write to config file: %w", err)
+	}
+	v.config = c
+	v.passKey = passKey
+	if !removeBackups {
+		return nil, nil
+	}
+	return v.removeBackups("sealed with the old master password")
+}
+
+// RotateKeys generates a new master key pair and re-encrypts every site with it,
+// including its notes and secret fields. Every site also gets a fresh key pair. The new config, index and passwords replace
+// the current ones in a single Storage.Replace. The backups in the storage are sealed with the old
+// master key. They are kept, along with a rolling backup of the vault before the rotation, unless
+// removeBackups is set: then they are removed once the new keys are in place and their paths returned.
+func (v *Vault) RotateKeys(removeBackups bool) (int, []string, error) {
+	if v.IsLocked() {
+		return 0, nil, ErrLocked
+	}
+	release, err := v.storage.Acquire()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer release()
+	if err = v.refreshConfig(); err != nil {
+		return 0, nil, err
+	}
+
+	sites, err := v.readSites()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// a backup of the vault about to be removed along with the others is not worth taking
+	if !removeBackups {
+		if err = v.autoBackup("rotate"); err != nil {
+			return 0, nil, err
+		}
+	}
+	masterPub, masterPriv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not generate master key pair: %w", err)
+	}
+
+	entries := make(map[string][]byte, len(sites))
+	for index, siteInfo := range sites {
+		encrypted, err := v.readEntry(siteInfo.Name)
+		if err != nil {
+			return 0, nil, err
+		}
+		password, ok := pc.BoxOpen(encrypted, &siteInfo.PubKey, v.masterPrivKey)
+		if !ok {
+			return 0, nil, fmt.Errorf("could not decrypt password of %s", siteInfo.Name)
+		}
+		// entries of an encrypted index are renamed after the new master key
+		entry := entryName(siteInfo.Name, v.config.EncryptedIndex, masterPriv)
+		var passSealed []byte
+		if siteInfo, passSealed, err = pc.ReEncrypt(siteInfo, string(password), masterPub); err != nil {
+			return 0, nil, err
+		}
+		entries[entry] = entryData(passSealed)
+		if siteInfo, err = v.resealDetails(siteInfo, masterPub); err != nil {
+			return 0, nil, fmt.Errorf("could not re-encrypt details of %s: %w", siteInfo.Name, err)
+		}
+		sites[index] = siteInfo
+	}
+
+	c := v.config
+	c.MasterPassKey = nil
+	c.MasterPubKey = *masterPub
+	// the key derived from the master password is reused, only the key pair changes
+	if c.MasterPrivKeySealed, err = pc.SecretboxSeal(v.passKey, masterPriv[:]); err != nil {
+		return 0, nil, fmt.Errorf("could not encrypt master key: %w", err)
+	}
+
+	config, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not marshal config file: %w", err)
+	}
+	index, err := marshalSites(sites, c.EncryptedIndex, masterPriv)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err = v.storage.Replace(config, index, entries); err != nil {
+		return 0, nil, fmt.Errorf("could not swap in the rotated vault, the previous vault was restored: %w", err)
+	}
+	v.config = c
+	v.masterPrivKey = masterPriv
+	if !removeBackups {
+		return len(sites), nil, nil
+	}
+	removed, err := v.removeBackups("sealed with the old master key")
+	return len(sites), removed, err
+}
+
+// Open a masterpass file sealed with the Argon2id hash string and re-seal it
+// with a key derived from the password and a freshly generated salt
+func (v *Vault) migrateLegacyConfig(password string) error {
+	match, err := argon2id.ComparePasswordAndHash(password, string(v.config.MasterPassKey))
+	if err != nil {
+		return fmt.Errorf("error comparing password: %w", err)
+	}
+	if !match {
+		return pc.ErrWrongMasterPassword
+	}
+
+	masterPrivKeySlice, ok := pc.SecretboxOpen(v.config.MasterPassKey, v.config.MasterPrivKeySealed)
+	if !ok {
+		return errors.New("failed to get master private key")
+	}
+	var masterPrivKey [32]byte
+	copy(masterPrivKey[:], masterPrivKeySlice)
+	v.masterPrivKey = &masterPrivKey
+
+	if _, err = v.ChangePassword(password, false); err != nil {
+		v.Lock()
+		return fmt.Errorf("could not migrate config file: %w", err)
+	}
+	return nil
+}
+
+// Run fn holding the storage lock, restoring the index and entries if it fails
+func (v *Vault) atomic(entries []string, fn func() error) error {
+	release, err := v.storage.Acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err = v.refreshConfig(); err != nil {
+		return err
+	}
+	return v.storage.Atomic(entries, fn)
+}
+
+// Read the config again once the lock is held, another process may have changed it since
+// the vault was opened. An unlocked vault fails with ErrVaultChanged if the master password
+// or key pair changed, as its keys no longer match the stored ones.
+func (v *Vault) refreshConfig() error {
+	unlocked := v.config
+	if err := v.readConfig(); err != nil {
+		return err
+	}
+	if v.IsLocked() {
+		return nil
+	}
+	if v.config.MasterPubKey != unlocked.MasterPubKey || !bytes.Equal(v.config.Salt, unlocked.Salt) {
+		v.config = unlocked
+		return ErrVaultChanged
+	}
+	return nil
+}
+
+func (v *Vault) readConfig() error {
+	data, err := v.storage.ReadConfig()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return io.ErrVaultNotInitialized
+		}
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+	var c io.ConfigFile
+	if err = json.Unmarshal(data, &c); err != nil {
+		return fmt.Errorf("could not unmarshal config file: %w", err)
+	}
+	if err = checkVersion(c.Version, CurrentVersion); err != nil {
+		return err
+	}
+	v.config = c
+	return nil
+}
+
+func (v *Vault) writeConfig(c io.ConfigFile) error {
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return fmt.Errorf("could not marshal config file: %w", err)
+	}
+	if err = v.storage.WriteConfig(data); err != nil {
+		return fmt.Errorf("could not write to config file: %w", err)
+	}
+	return nil
+}
+
+func (v *Vault) readSites() (s io.SiteFile, err error) {
+	data, err := v.storage.ReadIndex()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, io.ErrVaultNotInitialized
+		}
+		return nil, fmt.Errorf("could not read site file: %w", err)
+	}
+	return unmarshalSites(data, v.config.EncryptedIndex, v.masterPrivKey)
+}
+
+func (v *Vault) writeSites(s io.SiteFile) error {
+	data, err := marshalSites(s, v.config.EncryptedIndex, v.masterPrivKey)
+	if err != nil {
+		return err
+	}
+	if err = v.storage.WriteIndex(data); err != nil {
+		return fmt.Errorf("could not update sites.json: %w", err)
+	}
+	return nil
+}
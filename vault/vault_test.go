@@ -0,0 +1,507 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/jeremyphua/mypass/io"
+	"github.com/jeremyphua/mypass/pc"
+)
+
+const testPassword = "correct horse"
+
+// Create a vault in memory, unlocked
+func newTestVault(t *testing.T) (*Vault, *MemStorage) {
+	t.Helper()
+	s := NewMemStorage()
+	v, err := Create(s, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v, s
+}
+
+// Open another unlocked handle on the same storage, like a second mypass process would
+func openUnlocked(t *testing.T, s Storage, password string) *Vault {
+	t.Helper()
+	v := openLocked(t, s)
+	if err := v.Unlock(password); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func openLocked(t *testing.T, s Storage) *Vault {
+	t.Helper()
+	v, err := Open(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestStaleUnlockedVaultIsRefused(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(v *Vault) error
+	}{
+		{"rotate-keys", func(v *Vault) error { _, _, err := v.RotateKeys(false); return err }},
+		{"passwd", func(v *Vault) error { _, err := v.ChangePassword("new password", false); return err }},
+	}
+	ops := []struct {
+		name string
+		run  func(v *Vault) error
+	}{
+		{"passwd", func(v *Vault) error { _, err := v.ChangePassword("other password", false); return err }},
+		{"rotate-keys", func(v *Vault) error { _, _, err := v.RotateKeys(false); return err }},
+		{"put", func(v *Vault) error { return v.Put(site("web/github"), "pw2") }},
+		{"encrypt-index", func(v *Vault) error { _, err := v.EncryptIndex(true, false); return err }},
+	}
+	for _, tt := range tests {
+		for _, op := range ops {
+			t.Run(tt.name+" then "+op.name, func(t *testing.T) {
+				v, s := newTestVault(t)
+				if err := v.Add(site("web/github"), "pw"); err != nil {
+					t.Fatal(err)
+				}
+				stale := openUnlocked(t, s, testPassword)
+				if err := tt.change(v); err != nil {
+					t.Fatal(err)
+				}
+				if err := op.run(stale); !errors.Is(err, ErrVaultChanged) {
+					t.Fatalf("%s on a stale vault = %v, want ErrVaultChanged", op.name, err)
+				}
+				// the change made by the other process is intact
+				if _, got, err := v.Get("web/github"); err != nil || got != "pw" {
+					t.Fatalf("Get() after the refused %s = %q, %v", op.name, got, err)
+				}
+			})
+		}
+	}
+}
+
+func site(name string) io.SiteInfo {
+	return io.SiteInfo{Name: name, Username: "user"}
+}
+
+func TestVaultOperations(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func(v *Vault) error
+		// master password after op
+		password string
+		want     map[string]string
+		wantErr  error
+	}{
+		{
+			name: "add",
+			op:   func(v *Vault) error { return v.Add(site("work/mail"), "pw3") },
+			want: map[string]string{"web/github": "pw1", "bank": "pw2", "work/mail": "pw3"},
+		},
+		{
+			name:    "add duplicate",
+			op:      func(v *Vault) error { return v.Add(site("bank"), "pw3") },
+			wantErr: io.ErrDuplicateSite,
+		},
+		{
+			name:    "add invalid name",
+			op:      func(v *Vault) error { return v.Add(site("web/../bank"), "pw3") },
+			wantErr: io.ErrInvalidSiteName,
+		},
+		{
+			name: "put replaces",
+			op:   func(v *Vault) error { return v.Put(site("bank"), "pw3") },
+			want: map[string]string{"web/github": "pw1", "bank": "pw3"},
+		},
+		{
+			name: "rename",
+			op:   func(v *Vault) error { return v.Rename("web/github", "code/github") },
+			want: map[string]string{"code/github": "pw1", "bank": "pw2"},
+		},
+		{
+			name:    "rename to existing",
+			op:      func(v *Vault) error { return v.Rename("web/github", "bank") },
+			wantErr: io.ErrDuplicateSite,
+		},
+		{
+			name:    "rename missing",
+			op:      func(v *Vault) error { return v.Rename("nope", "other") },
+			wantErr: io.ErrSiteNotFound,
+		},
+		{
+			name: "delete",
+			op:   func(v *Vault) error { return v.Delete("bank") },
+			want: map[string]string{"web/github": "pw1"},
+		},
+		{
+			name:    "delete missing",
+			op:      func(v *Vault) error { return v.Delete("nope") },
+			wantErr: io.ErrSiteNotFound,
+		},
+		{
+			name: "rotate-keys",
+			op: func(v *Vault) error {
+				n, _, err := v.RotateKeys(false)
+				if err == nil && n != 2 {
+					t.Errorf("RotateKeys() = %d sites, want 2", n)
+				}
+				return err
+			},
+			want: map[string]string{"web/github": "pw1", "bank": "pw2"},
+		},
+		{
+			name:     "passwd",
+			op:       func(v *Vault) error { _, err := v.ChangePassword("new password", false); return err },
+			password: "new password",
+			want:     map[string]string{"web/github": "pw1", "bank": "pw2"},
+		},
+	}
+	for _, encrypted := range []bool{false, true} {
+		for _, tt := range tests {
+			name := tt.name
+			if encrypted {
+				name += " with encrypted index"
+			}
+			t.Run(name, func(t *testing.T) {
+				v, s := newTestVault(t)
+				if _, err := v.EncryptIndex(encrypted, false); err != nil {
+					t.Fatal(err)
+				}
+				for name, password := range map[string]string{"web/github": "pw1", "bank": "pw2"} {
+					if err := v.Add(site(name), password); err != nil {
+						t.Fatal(err)
+					}
+				}
+				err := tt.op(v)
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("got error %v, want %v", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				password := testPassword
+				if tt.password != "" {
+					password = tt.password
+					if err = openLocked(t, s).Unlock(testPassword); !errors.Is(err, pc.ErrWrongMasterPassword) {
+						t.Errorf("Unlock() with the old master password = %v, want %v", err, pc.ErrWrongMasterPassword)
+					}
+				}
+				// a fresh handle only sees what was written to the storage
+				reopened := openUnlocked(t, s, password)
+				sites, err := reopened.List()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(sites) != len(tt.want) {
+					t.Errorf("List() has %d sites, want %d", len(sites), len(tt.want))
+				}
+				for name, want := range tt.want {
+					if _, got, err := reopened.Get(name); err != nil || got != want {
+						t.Errorf("Get(%q) = %q, %v, want %q", name, got, err, want)
+					}
+				}
+				entries, err := reopened.Entries()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(entries) != len(tt.want) {
+					t.Errorf("storage has %d entries, want %d", len(entries), len(tt.want))
+				}
+			})
+		}
+	}
+}
+
+func TestStoredArgon2Params(t *testing.T) {
+	v, s := newTestVault(t)
+	if err := v.Add(site("bank"), "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if c := readTestConfig(t, s); c.Argon2 == nil || *c.Argon2 != pc.DefaultArgon2Params() {
+		t.Fatalf("new vault stored Argon2 params %+v, want %+v", c.Argon2, pc.DefaultArgon2Params())
+	}
+
+	// a vault sealed with other params, like defaults of an older or newer mypass, still unlocks
+	custom := io.Argon2Params{Memory: 8 * 1024, Iterations: 2, Parallelism: 1}
+	c := readTestConfig(t, s)
+	salt, sealed, err := pc.SealMasterPrivKey(testPassword, custom, v.masterPrivKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Salt, c.MasterPrivKeySealed, c.Argon2 = salt, sealed, &custom
+	writeJSONConfig(t, s, c)
+	v = openUnlocked(t, s, testPassword)
+	if _, got, err := v.Get("bank"); err != nil || got != "pw" {
+		t.Fatalf("Get() = %q, %v with custom Argon2 params", got, err)
+	}
+
+	// changing the master password moves to the current defaults
+	if _, err = v.ChangePassword("new password", false); err != nil {
+		t.Fatal(err)
+	}
+	if c = readTestConfig(t, s); c.Argon2 == nil || *c.Argon2 != pc.DefaultArgon2Params() {
+		t.Fatalf("passwd stored Argon2 params %+v, want %+v", c.Argon2, pc.DefaultArgon2Params())
+	}
+	openUnlocked(t, s, "new password")
+}
+
+func readTestConfig(t *testing.T, s Storage) io.ConfigFile {
+	t.Helper()
+	data, err := s.ReadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c io.ConfigFile
+	if err = json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func writeJSONConfig(t *testing.T, s Storage, c io.ConfigFile) {
+	t.Helper()
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.WriteConfig(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// A masterpass written before the salt was stored seals the master private key with
+// the Argon2id hash string itself, it is sealed again with a derived key on the first unlock
+func TestMigrateLegacyConfig(t *testing.T) {
+	v, s := newTestVault(t)
+	if err := v.Add(site("bank"), "pw"); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := argon2id.CreateHash(testPassword, &argon2id.Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := readTestConfig(t, s)
+	c.Salt, c.Argon2, c.MasterPassKey = nil, nil, []byte(hash)
+	if c.MasterPrivKeySealed, err = pc.SecretboxSeal(c.MasterPassKey, v.masterPrivKey[:]); err != nil {
+		t.Fatal(err)
+	}
+	writeJSONConfig(t, s, c)
+
+	if err = openLocked(t, s).Unlock("wrong password"); !errors.Is(err, pc.ErrWrongMasterPassword) {
+		t.Fatalf("Unlock() with a wrong password = %v, want ErrWrongMasterPassword", err)
+	}
+	if c = readTestConfig(t, s); !c.IsLegacy() {
+		t.Fatal("a wrong password migrated the config")
+	}
+
+	v = openUnlocked(t, s, testPassword)
+	if _, got, err := v.Get("bank"); err != nil || got != "pw" {
+		t.Fatalf("Get() = %q, %v after migrating the config", got, err)
+	}
+	c = readTestConfig(t, s)
+	if c.IsLegacy() || len(c.MasterPassKey) > 0 || len(c.Salt) == 0 || c.Argon2 == nil || *c.Argon2 != pc.DefaultArgon2Params() {
+		t.Fatalf("migrated config has salt %x, hash %q and Argon2 params %+v", c.Salt, c.MasterPassKey, c.Argon2)
+	}
+
+	// the next unlock derives the key from the stored salt
+	if err = openLocked(t, s).Unlock("wrong password"); !errors.Is(err, pc.ErrWrongMasterPassword) {
+		t.Fatalf("Unlock() of the migrated config with a wrong password = %v, want ErrWrongMasterPassword", err)
+	}
+	if _, got, err := openUnlocked(t, s, testPassword).Get("bank"); err != nil || got != "pw" {
+		t.Fatalf("Get() = %q, %v after reopening the migrated vault", got, err)
+	}
+}
+
+func TestRotateKeysRetiresOldKey(t *testing.T) {
+	dir := newFileVault(t)
+	s := NewFileStorage(dir, DefaultFileOptions())
+	v := openUnlocked(t, s, testPassword)
+	// a rolling backup next to the backup folder written by newFileVault
+	if err := v.Rename("work/mail", "work/email"); err != nil {
+		t.Fatal(err)
+	}
+	oldSite, err := v.Site("work/email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldEntry, err := v.readEntry("work/email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldPriv := *v.masterPrivKey
+
+	_, removed, err := v.RotateKeys(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the backups are kept unless asked, with one more taken by the rotation
+	rolling, err := filepath.Glob(filepath.Join(dir, BackupFolderName, AutoBackupFolderName, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, BackupFolderName, "test")); err != nil || len(removed) > 0 || len(rolling) != 2 {
+		t.Errorf("RotateKeys(false) removed %v and kept %v, %v, want the test backup and 2 rolling backups kept", removed, rolling, err)
+	}
+	v = openUnlocked(t, NewFileStorage(dir, DefaultFileOptions()), testPassword)
+	if _, ok := pc.BoxOpen(oldEntry, &oldSite.PubKey, v.masterPrivKey); ok {
+		t.Error("the entry sealed before the rotation opens with the new master key")
+	}
+	newSite, err := v.Site("work/email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newEntry, err := v.readEntry("work/email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pc.BoxOpen(newEntry, &newSite.PubKey, &oldPriv); ok {
+		t.Error("the entry sealed by the rotation opens with the old master key")
+	}
+	if _, got, err := v.Get("work/email"); err != nil || got != "secret" {
+		t.Errorf("Get() = %q, %v after the rotation", got, err)
+	}
+
+	// the backups sealed with the old keys are gone when asked, without taking one more
+	if _, removed, err = v.RotateKeys(true); err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 3 {
+		t.Errorf("RotateKeys(true) removed %v, want the test backup and 2 rolling backups", removed)
+	}
+	left, err := filepath.Glob(filepath.Join(dir, BackupFolderName, "*", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) > 0 {
+		t.Errorf("backups left after the rotation: %v", left)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	v, s := newTestVault(t)
+	if err := v.Add(site("bank"), "pw"); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := s.ReadEntry("bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = openLocked(t, s).ChangePassword("new password", false); !errors.Is(err, ErrLocked) {
+		t.Fatalf("ChangePassword() on a locked vault = %v, want ErrLocked", err)
+	}
+	if _, err = v.ChangePassword("new password", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = openLocked(t, s).Unlock(testPassword); !errors.Is(err, pc.ErrWrongMasterPassword) {
+		t.Fatalf("Unlock() with the old password = %v, want ErrWrongMasterPassword", err)
+	}
+	// only the master private key is sealed again, the passwords are left as they were
+	if data, err := s.ReadEntry("bank"); err != nil || !bytes.Equal(data, sealed) {
+		t.Fatalf("the password of bank was rewritten: %v", err)
+	}
+	for _, v := range []*Vault{v, openUnlocked(t, s, "new password")} {
+		if _, password, err := v.Get("bank"); err != nil || password != "pw" {
+			t.Fatalf("Get(bank) = %q, %v after changing the master password", password, err)
+		}
+	}
+}
+
+func TestChangePasswordRemovesBackups(t *testing.T) {
+	dir := newFileVault(t)
+	v := openUnlocked(t, NewFileStorage(dir, DefaultFileOptions()), testPassword)
+	// the backup written by newFileVault still opens with the old password
+	removed, err := v.ChangePassword("new password", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backup := filepath.Join(dir, BackupFolderName, "test")
+	if _, err = os.Stat(backup); err != nil || len(removed) > 0 {
+		t.Fatalf("ChangePassword(false) removed %v, %v, want the test backup kept", removed, err)
+	}
+	if removed, err = v.ChangePassword("other password", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(backup); !os.IsNotExist(err) || len(removed) != 1 {
+		t.Fatalf("ChangePassword(true) removed %v, %v, want the test backup removed", removed, err)
+	}
+	openUnlocked(t, NewFileStorage(dir, DefaultFileOptions()), "other password")
+}
+
+func TestTimestamps(t *testing.T) {
+	v, _ := newTestVault(t)
+	if err := v.Add(site("bank"), "pw1"); err != nil {
+		t.Fatal(err)
+	}
+	added, err := v.Site("bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.Created.IsZero() || !added.PasswordChanged.Equal(added.Created) {
+		t.Fatalf("Created = %v, PasswordChanged = %v after Add()", added.Created, added.PasswordChanged)
+	}
+
+	// a new password keeps the creation time
+	time.Sleep(10 * time.Millisecond)
+	if err = v.Put(site("bank"), "pw2"); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := v.Site("bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed.Created.Equal(added.Created) || !changed.PasswordChanged.After(added.PasswordChanged) {
+		t.Fatalf("Created = %v, PasswordChanged = %v after Put(), was %v", changed.Created, changed.PasswordChanged, added.Created)
+	}
+
+	// changing the username keeps both
+	changed.Username = "other"
+	if err = v.Update(changed); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := v.Site("bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.Created.Equal(changed.Created) || !updated.PasswordChanged.Equal(changed.PasswordChanged) {
+		t.Fatalf("Update() changed the timestamps to %v, %v", updated.Created, updated.PasswordChanged)
+	}
+}
+
+func TestMarkShown(t *testing.T) {
+	v, s := newTestVault(t)
+	if err := v.Add(site("bank"), "pw"); err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now()
+	if err := v.MarkShown("bank"); err != nil {
+		t.Fatal(err)
+	}
+	si, err := v.Site("bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if si.LastShown.Before(before.Add(-time.Second)) {
+		t.Fatalf("LastShown = %v, want about %v", si.LastShown, before)
+	}
+
+	// another process busy with the vault is not waited for
+	release, err := s.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if err = v.MarkShown("bank"); !errors.Is(err, ErrInUse) {
+		t.Fatalf("MarkShown() while the vault is held = %v, want ErrInUse", err)
+	}
+}